@@ -0,0 +1,164 @@
+package protoyaml
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tommie/protoyaml-go/protoyamlpb"
+)
+
+// fieldAliases returns the former names of a field, as set with the
+// (protoyaml.field).aliases option. The option is read reflectively,
+// since descriptors built at runtime may hold it as a dynamic message.
+func fieldAliases(fd protoreflect.FieldDescriptor) []string {
+	opts, ok := fd.Options().(*descriptorpb.FieldOptions)
+	if !ok || opts == nil {
+		return nil
+	}
+	if len(opts.ProtoReflect().GetUnknown()) > 0 {
+		// Options of descriptors built before protoyamlpb was
+		// registered keep the extension as unknown fields.
+		bs, err := proto.Marshal(opts)
+		if err != nil {
+			return nil
+		}
+		opts = &descriptorpb.FieldOptions{}
+		if err := proto.Unmarshal(bs, opts); err != nil {
+			return nil
+		}
+	}
+
+	var aliases []string
+	opts.ProtoReflect().Range(func(xd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if xd.FullName() != protoyamlpb.E_Field.TypeDescriptor().FullName() {
+			return true
+		}
+		m := v.Message()
+		afd := m.Descriptor().Fields().ByName("aliases")
+		if afd == nil || !afd.IsList() || afd.Kind() != protoreflect.StringKind {
+			return false
+		}
+		l := m.Get(afd).List()
+		for i := 0; i < l.Len(); i++ {
+			aliases = append(aliases, l.Get(i).String())
+		}
+		return false
+	})
+	return aliases
+}
+
+// fieldsByAlias returns the fields of md by alias. If fields share an
+// alias, the first one wins.
+func fieldsByAlias(md protoreflect.MessageDescriptor) map[string]protoreflect.FieldDescriptor {
+	byAlias := map[string]protoreflect.FieldDescriptor{}
+	fds := md.Fields()
+	for i := 0; i < fds.Len(); i++ {
+		for _, alias := range fieldAliases(fds.Get(i)) {
+			if _, ok := byAlias[alias]; !ok {
+				byAlias[alias] = fds.Get(i)
+			}
+		}
+	}
+	return byAlias
+}
+
+// findAliasedField returns the field that has key as an alias, or nil.
+// The options are read on every call. See aliasCache for repeated
+// lookups.
+func findAliasedField(md protoreflect.MessageDescriptor, key string) protoreflect.FieldDescriptor {
+	return fieldsByAlias(md)[key]
+}
+
+// An aliasCache maps message descriptors to their fields by alias, so
+// the options of each message are only read once. Caches are held by a
+// Decoder, or for a single update, rather than globally, so
+// descriptors built at runtime can be garbage collected.
+type aliasCache map[protoreflect.MessageDescriptor]map[string]protoreflect.FieldDescriptor
+
+// find returns the field of md that has key as an alias, or nil.
+func (c aliasCache) find(md protoreflect.MessageDescriptor, key string) protoreflect.FieldDescriptor {
+	byAlias, ok := c[md]
+	if !ok {
+		byAlias = fieldsByAlias(md)
+		c[md] = byAlias
+	}
+	return byAlias[key]
+}
+
+// RenameAliases updates a YAML document in place, so that keys using
+// a field alias are replaced by the current field name. The node is a
+// document or a mapping to be decoded as md. Only key values change,
+// so comments, key order, anchors and quoting are kept. When encoding
+// the result, use DocumentIndent to also keep the indentation. Returns
+// the number of renamed keys.
+func RenameAliases(n *yaml.Node, md protoreflect.MessageDescriptor) int {
+	return renameAliases(n, md, map[*yaml.Node]bool{})
+}
+
+// renameAliases renames keys in a message mapping, and recurses into
+// message values. Nodes in visited are skipped, so anchored nodes are
+// only renamed once.
+func renameAliases(n *yaml.Node, md protoreflect.MessageDescriptor, visited map[*yaml.Node]bool) int {
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Kind != yaml.MappingNode || visited[n] || isKnownType(md) {
+		return 0
+	}
+	visited[n] = true
+
+	var count int
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		kn, v := n.Content[i], n.Content[i+1]
+		if isMergeKey(kn) {
+			if v.Kind == yaml.SequenceNode {
+				for _, v := range v.Content {
+					count += renameAliases(v, md, visited)
+				}
+			} else {
+				count += renameAliases(v, md, visited)
+			}
+			continue
+		}
+
+		fd := md.Fields().ByName(protoreflect.Name(kn.Value))
+		if fd == nil {
+			fd = findAliasedField(md, kn.Value)
+			if fd == nil {
+				continue
+			}
+			kn.Value = string(fd.Name())
+			count++
+		}
+
+		if v.Kind == yaml.AliasNode {
+			v = v.Alias
+		}
+		switch {
+		case fd.IsMap():
+			if !isMessageKind(fd.MapValue()) || v.Kind != yaml.MappingNode {
+				continue
+			}
+			for j := 1; j < len(v.Content); j += 2 {
+				count += renameAliases(v.Content[j], fd.MapValue().Message(), visited)
+			}
+
+		case fd.IsList():
+			if !isMessageKind(fd) || v.Kind != yaml.SequenceNode {
+				continue
+			}
+			for _, e := range v.Content {
+				count += renameAliases(e, fd.Message(), visited)
+			}
+
+		case isMessageKind(fd):
+			count += renameAliases(v, fd.Message(), visited)
+		}
+	}
+	return count
+}
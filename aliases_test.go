@@ -0,0 +1,143 @@
+package protoyaml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tommie/protoyaml-go/internal/testproto"
+	"github.com/tommie/protoyaml-go/protoyamlpb"
+)
+
+func TestDecoderFieldAlias(t *testing.T) {
+	d := NewDecoder(strings.NewReader("anold: a\namessage:\n  anolder: b\n"))
+	d.RecordSourceInfo("a.yaml")
+	var gotW []Warning
+	d.Warnings(func(w Warning) { gotW = append(gotW, w) })
+
+	var got testproto.Message
+	if err := d.Decode(&got); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	want := &testproto.Message{Arenamed: "a", Amessage: &testproto.Message{Arenamed: "b"}}
+	if diff := cmp.Diff(want, &got, protocmp.Transform()); diff != "" {
+		t.Errorf("Decode: +got, -want:\n%s", diff)
+	}
+
+	wantW := []Warning{
+		{WarnFieldAlias, Position{"a.yaml", 1, 1}, "arenamed", "field name anold is deprecated, use arenamed"},
+		{WarnFieldAlias, Position{"a.yaml", 3, 3}, "amessage.arenamed", "field name anolder is deprecated, use arenamed"},
+	}
+	if diff := cmp.Diff(wantW, gotW); diff != "" {
+		t.Errorf("Warnings: +got, -want:\n%s", diff)
+	}
+}
+
+func TestDynamicDecoderFieldAlias(t *testing.T) {
+	fds := fileDescriptorSet(testproto.File_internal_testproto_test_proto)
+	d, err := NewDynamicDecoder(strings.NewReader("anold: a\n"), fds, "protoyaml.test.Message")
+	if err != nil {
+		t.Fatalf("NewDynamicDecoder failed: %v", err)
+	}
+
+	got, err := d.Decode()
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if got, want := got.Get(got.Descriptor().Fields().ByName("arenamed")).String(), "a"; got != want {
+		t.Errorf("Decode: got %q, want %q", got, want)
+	}
+}
+
+func TestDecoderFieldAliasDynamicOption(t *testing.T) {
+	// Descriptors compiled at runtime, e.g. by protocompile, can hold
+	// the option as a dynamic message.
+	xt := dynamicpb.NewExtensionType(protoyamlpb.E_Field.TypeDescriptor().Descriptor())
+	xv := xt.New().Message()
+	xv.Mutable(xv.Descriptor().Fields().ByName("aliases")).List().Append(protoreflect.ValueOfString("old"))
+	opts := &descriptorpb.FieldOptions{}
+	opts.ProtoReflect().Set(xt.TypeDescriptor(), protoreflect.ValueOfMessage(xv))
+
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("dynamic.proto"),
+		Package: proto.String("protoyaml.dynamic"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Message"),
+			Field: []*descriptorpb.FieldDescriptorProto{{
+				Name:    proto.String("current"),
+				Number:  proto.Int32(1),
+				Type:    descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+				Label:   descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Options: opts,
+			}},
+		}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewFile failed: %v", err)
+	}
+	md := fd.Messages().Get(0)
+
+	got := dynamicpb.NewMessage(md)
+	if err := Unmarshal([]byte("old: a\n"), got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got, want := got.Get(md.Fields().ByName("current")).String(), "a"; got != want {
+		t.Errorf("Unmarshal: got %q, want %q", got, want)
+	}
+}
+
+func TestRenameAliases(t *testing.T) {
+	in := `# Config.
+anold: a # The old name.
+amessage: &m
+  anolder: b
+  arenamed: c
+arepeated_message:
+  - anold: d
+  - *m
+astring_message_map:
+  x: {anold: e}
+<<: {anold: f}
+unknown: {anold: g}
+`
+	want := `# Config.
+arenamed: a # The old name.
+amessage: &m
+    arenamed: b
+    arenamed: c
+arepeated_message:
+    - arenamed: d
+    - *m
+astring_message_map:
+    x: {arenamed: e}
+!!merge <<: {arenamed: f}
+unknown: {anold: g}
+`
+
+	var n yaml.Node
+	if err := yaml.Unmarshal([]byte(in), &n); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if got, want := RenameAliases(&n, (&testproto.Message{}).ProtoReflect().Descriptor()), 5; got != want {
+		t.Errorf("RenameAliases: got %d, want %d", got, want)
+	}
+
+	bs, err := yaml.Marshal(&n)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if diff := cmp.Diff(want, string(bs)); diff != "" {
+		t.Errorf("RenameAliases: +got, -want:\n%s", diff)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tommie/protoyaml-go"
+)
+
+// runBreaking checks that YAML files mean the same with a new schema
+// as with the old one. It reports documents that no longer decode,
+// and values that are read differently.
+func runBreaking(env *cmdEnv, args []string) error {
+	fs := newFlagSet(env, "breaking", "file|directory...")
+	var oldsf, newsf schemaFlags
+	fs.Var(&oldsf.descSets, "old", "a binary FileDescriptorSet `file` with the old schema (repeatable)")
+	fs.Var(&newsf.descSets, "new", "a binary FileDescriptorSet `file` with the new schema (repeatable)")
+	typeName := fs.String("type", "", "the full `name` of the message, for files not matched by -map")
+	var maps stringList
+	fs.Var(&maps, "map", "a `glob=name` pair selecting the message for matching files (repeatable)")
+	format := fs.String("format", "text", "the output `format`: text, json or github")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(oldsf.descSets) == 0 || len(newsf.descSets) == 0 {
+		return fmt.Errorf("want both -old and -new")
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no files to check")
+	}
+
+	olds, err := oldsf.load()
+	if err != nil {
+		return err
+	}
+	news, err := newsf.load()
+	if err != nil {
+		return err
+	}
+	oldtm, err := newTypeMap(olds, maps, *typeName)
+	if err != nil {
+		return fmt.Errorf("old schema: %w", err)
+	}
+	newtm, err := newTypeMap(news, maps, *typeName)
+	if err != nil {
+		return fmt.Errorf("new schema: %w", err)
+	}
+
+	names, err := yamlFiles(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	var diags []diagnostic
+	for _, name := range names {
+		oldmt, err := oldtm.lookup(name)
+		if err != nil {
+			diags = append(diags, diagnostic{File: name, Severity: severityError, Message: err.Error()})
+			continue
+		}
+		// The globs are the same, so the new map also matches.
+		newmt, _ := newtm.lookup(name)
+		diags = append(diags, breakingFile(env, olds, news, name, oldmt, newmt)...)
+	}
+
+	if err := writeDiagnostics(env.stdout, *format, diags); err != nil {
+		return err
+	}
+	for _, diag := range diags {
+		if diag.Severity == severityError {
+			return errFailed
+		}
+	}
+	return nil
+}
+
+// breakingFile checks each document in a file, and returns the
+// problems found with the new schema. Documents that don't decode
+// with the old schema are reported as warnings, and not checked.
+func breakingFile(env *cmdEnv, olds, news *schema, name string, oldmt, newmt protoreflect.MessageType) []diagnostic {
+	r, err := openInput(env, name)
+	if err != nil {
+		return []diagnostic{{File: name, Severity: severityError, Message: err.Error()}}
+	}
+	defer r.Close()
+
+	var diags []diagnostic
+	yd := yaml.NewDecoder(r)
+	for {
+		var doc yaml.Node
+		if err := yd.Decode(&doc); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return append(diags, syntaxDiagnostic(name, err))
+		}
+		diags = append(diags, breakingDocument(olds, news, name, &doc, oldmt, newmt)...)
+	}
+	return diags
+}
+
+// breakingDocument decodes a document with both schemas. The old
+// message is converted to the new schema through the binary format,
+// like a new program reading old data would, and compared to the new
+// message. Any difference means a value is now read as something
+// else, e.g. because a field or enum value changed its number.
+func breakingDocument(olds, news *schema, name string, doc *yaml.Node, oldmt, newmt protoreflect.MessageType) []diagnostic {
+	oldWarnings := map[string]bool{}
+	oldd := newSchemaDecoder(olds, name)
+	oldd.Warnings(func(w protoyaml.Warning) {
+		oldWarnings[w.Position.String()+w.Message] = true
+	})
+	om := oldmt.New()
+	if err := oldd.DecodeNode(doc, om); err != nil {
+		diag := errorDiagnostic(name, doc, err)
+		diag.Severity = severityWarning
+		diag.Message = "does not decode with the old schema: " + diag.Message
+		return []diagnostic{diag}
+	}
+
+	var diags []diagnostic
+	newd := newSchemaDecoder(news, name)
+	newd.Warnings(func(w protoyaml.Warning) {
+		// Only warnings added by the new schema are interesting.
+		if oldWarnings[w.Position.String()+w.Message] {
+			return
+		}
+		diags = append(diags, diagnostic{
+			File:     w.Position.File,
+			Line:     w.Position.Line,
+			Column:   w.Position.Column,
+			Severity: severityWarning,
+			Path:     w.Path,
+			Message:  w.Message,
+		})
+	})
+	nm := newmt.New()
+	if err := newd.DecodeNode(doc, nm); err != nil {
+		return append(diags, errorDiagnostic(name, doc, err))
+	}
+
+	bs, err := proto.MarshalOptions{AllowPartial: true}.Marshal(om.Interface())
+	if err != nil {
+		return append(diags, errorDiagnostic(name, doc, err))
+	}
+	rm := newmt.New()
+	if err := (proto.UnmarshalOptions{AllowPartial: true, Resolver: news.types}).Unmarshal(bs, rm.Interface()); err != nil {
+		return append(diags, errorDiagnostic(name, doc, fmt.Errorf("cannot read old data with the new schema: %w", err)))
+	}
+	cs, err := protoyaml.Diff(rm.Interface(), nm.Interface())
+	if err != nil {
+		return append(diags, errorDiagnostic(name, doc, err))
+	}
+	for _, c := range cs {
+		pos := lookupPosition(newd.SourceInfo(), c.Path, doc)
+		diags = append(diags, diagnostic{
+			File:     name,
+			Line:     pos.Line,
+			Column:   pos.Column,
+			Severity: severityError,
+			Path:     c.Path,
+			Message:  "changes meaning: " + c.String(),
+		})
+	}
+	return diags
+}
+
+// newSchemaDecoder returns a decoder resolving types in s, and
+// recording positions in the named file.
+func newSchemaDecoder(s *schema, name string) *protoyaml.Decoder {
+	d := protoyaml.NewDecoder(nil)
+	d.RecordSourceInfo(name)
+	d.MessageTypeResolver(s.types)
+	d.ExtensionTypeResolver(s.types)
+	return d
+}
+
+// lookupPosition returns the position of a field path, or of its
+// closest parent that was written. Values only set in the old message
+// have no position, and fall back to the document.
+func lookupPosition(si *protoyaml.SourceInfo, path string, doc *yaml.Node) protoyaml.Position {
+	var best string
+	var pos protoyaml.Position
+	found := false
+	for p, ppos := range si.Positions {
+		if p != path && !strings.HasPrefix(path, p+".") && !strings.HasPrefix(path, p+"[") {
+			continue
+		}
+		if !found || len(p) > len(best) {
+			best, pos, found = p, ppos, true
+		}
+	}
+	if !found {
+		return protoyaml.Position{Line: doc.Line, Column: doc.Column}
+	}
+	return pos
+}
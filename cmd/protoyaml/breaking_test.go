@@ -0,0 +1,61 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestBreaking(t *testing.T) {
+	oldds := writeDescriptorSet(t)
+	newds := writeEditedDescriptorSet(t, func(fdp *descriptorpb.FileDescriptorProto) {
+		if fdp.GetName() != "internal/testproto/test.proto" {
+			return
+		}
+		for _, md := range fdp.MessageType {
+			if md.GetName() != "Message" {
+				continue
+			}
+			var fields []*descriptorpb.FieldDescriptorProto
+			for _, f := range md.Field {
+				switch f.GetName() {
+				case "astring":
+					continue
+				case "abool":
+					f.Options = &descriptorpb.FieldOptions{Deprecated: proto.Bool(true)}
+				}
+				fields = append(fields, f)
+			}
+			md.Field = fields
+		}
+		for _, ed := range fdp.EnumType {
+			for _, v := range ed.Value {
+				if v.GetName() == "ONE" {
+					v.Number = proto.Int32(3)
+				}
+			}
+		}
+	})
+	dir := writeFiles(t, map[string]string{
+		"a.yaml":    "anint32: 1\nanenum: ONE\n",
+		"b.yaml":    "anint32: 1\n---\nabool: true\nastring: x\n",
+		"ok.yaml":   "anint32: 1\nanenum: TWO\n",
+		"old.yaml":  "nosuch: 1\n",
+		"other.txt": "not checked",
+	})
+
+	status, got, stderr := runCommand(t, "", "breaking", "-old", oldds, "-new", newds, "-type", "protoyaml.test.Message", dir)
+	if status != 1 {
+		t.Fatalf("run: got status %d, want 1: %s", status, stderr)
+	}
+	want := filepath.Join(dir, "a.yaml") + ":2:1: error: changes meaning: ~ anenum: 1 -> ONE\n" +
+		filepath.Join(dir, "b.yaml") + ":3:1: warning: field protoyaml.test.Message.abool is deprecated\n" +
+		filepath.Join(dir, "b.yaml") + ":4:1: error: unknown field: protoyaml.test.Message.astring\n" +
+		filepath.Join(dir, "old.yaml") + ":1:1: warning: does not decode with the old schema: unknown field: protoyaml.test.Message.nosuch\n"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("run: +got, -want:\n%s", diff)
+	}
+}
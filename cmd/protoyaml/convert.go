@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// runConvert reads messages from each input, and writes them all to
+// one output stream. An output file is only written once all inputs
+// have been converted, so it is left alone on errors, and can be one
+// of the inputs.
+func runConvert(env *cmdEnv, args []string) error {
+	fs := newFlagSet(env, "convert", "[file...]")
+	var sf schemaFlags
+	sf.register(fs)
+	typeName := fs.String("type", "", "the full `name` of the message (optional for YAML input with @type)")
+	from := fs.String("from", "", "the input `format`: yaml, json, binary or text (default from the file extension, or yaml)")
+	to := fs.String("to", formatYAML, "the output `format`: yaml, json, binary or text")
+	out := fs.String("o", "-", "the output `file`")
+	delimited := fs.Bool("delimited", false, "read and write binary streams as size-delimited messages")
+	comments := fs.Bool("comments", false, "add the .proto comments of fields to YAML output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	s, err := sf.load()
+	if err != nil {
+		return err
+	}
+	var mt protoreflect.MessageType
+	if *typeName != "" {
+		mt, err = s.messageType(*typeName)
+		if err != nil {
+			return err
+		}
+	}
+
+	w := env.stdout
+	var buf bytes.Buffer
+	if *out != "-" {
+		w = &buf
+	}
+	mw, err := newMessageWriter(s, w, *to, *delimited)
+	if err != nil {
+		return err
+	}
+	if yw, ok := mw.(yamlWriter); ok {
+		yw.e.SourceComments(*comments)
+	}
+
+	for _, name := range inputNames(fs.Args()) {
+		format := *from
+		if format == "" {
+			format = formatFromName(name, formatYAML)
+		}
+		if err := convertFile(env, s, mw, name, format, mt, *delimited); err != nil {
+			return err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+	if *out != "-" {
+		return os.WriteFile(*out, buf.Bytes(), 0666)
+	}
+	return nil
+}
+
+// convertFile writes all messages in the named file to mw.
+func convertFile(env *cmdEnv, s *schema, mw messageWriter, name, format string, mt protoreflect.MessageType, delimited bool) error {
+	r, err := openInput(env, name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	mr, err := newMessageReader(s, r, name, format, mt, delimited)
+	if err != nil {
+		return err
+	}
+	for {
+		m, err := mr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := mw.Write(m); err != nil {
+			return err
+		}
+	}
+}
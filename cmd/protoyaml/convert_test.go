@@ -0,0 +1,189 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestConvert(t *testing.T) {
+	ds := writeDescriptorSet(t)
+	const in = "anint32: 42\nastring: hello\n---\nanenum: ONE\n"
+
+	tsts := []struct {
+		Name string
+		Args []string
+		Want string
+	}{
+		{"yamlToYAML", nil, "anint32: 42\nastring: hello\n---\nanenum: ONE\n"},
+		{"yamlToJSON", []string{"-to", "json"}, "{\"anint32\":42,\"astring\":\"hello\"}\n{\"anenum\":\"ONE\"}\n"},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Name, func(t *testing.T) {
+			args := append([]string{"convert", "-d", ds, "-type", "protoyaml.test.Message"}, tst.Args...)
+			status, got, stderr := runCommand(t, in, args...)
+			if status != 0 {
+				t.Fatalf("run failed: %d: %s", status, stderr)
+			}
+			if slices.Contains(tst.Args, formatJSON) {
+				got = compactJSON(t, got)
+			}
+			if diff := cmp.Diff(tst.Want, got); diff != "" {
+				t.Errorf("run: +got, -want:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConvertRoundTrip(t *testing.T) {
+	ds := writeDescriptorSet(t)
+	const in = "anint32: 42\nastring_int32_map:\n    x: 1\narepeated_message:\n    - astring: a\n"
+
+	for _, format := range []string{formatJSON, formatBinary, formatText} {
+		t.Run(format, func(t *testing.T) {
+			status, mid, stderr := runCommand(t, in, "convert", "-d", ds, "-type", "protoyaml.test.Message", "-to", format)
+			if status != 0 {
+				t.Fatalf("run failed: %d: %s", status, stderr)
+			}
+
+			status, got, stderr := runCommand(t, mid, "convert", "-d", ds, "-type", "protoyaml.test.Message", "-from", format)
+			if status != 0 {
+				t.Fatalf("run failed: %d: %s", status, stderr)
+			}
+			if diff := cmp.Diff(in, got); diff != "" {
+				t.Errorf("run: +got, -want:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConvertDelimited(t *testing.T) {
+	ds := writeDescriptorSet(t)
+	const in = "anint32: 1\n---\nanint32: 2\n"
+
+	status, _, stderr := runCommand(t, in, "convert", "-d", ds, "-type", "protoyaml.test.Message", "-to", "binary")
+	if status != 1 || !strings.Contains(stderr, "can only hold one message") {
+		t.Errorf("run: got %d, %q, want a single message error", status, stderr)
+	}
+
+	status, mid, stderr := runCommand(t, in, "convert", "-d", ds, "-type", "protoyaml.test.Message", "-to", "binary", "-delimited")
+	if status != 0 {
+		t.Fatalf("run failed: %d: %s", status, stderr)
+	}
+	status, got, stderr := runCommand(t, mid, "convert", "-d", ds, "-type", "protoyaml.test.Message", "-from", "binary", "-delimited")
+	if status != 0 {
+		t.Fatalf("run failed: %d: %s", status, stderr)
+	}
+	if diff := cmp.Diff(in, got); diff != "" {
+		t.Errorf("run: +got, -want:\n%s", diff)
+	}
+}
+
+func TestConvertProtoSource(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"conf/conf.proto": `syntax = "proto3";
+package conf;
+import "google/protobuf/duration.proto";
+import "protoyamlpb/options.proto";
+message Config {
+  string name = 1 [(protoyaml.field).aliases = "title"];
+  google.protobuf.Duration timeout = 2;
+}
+`,
+		"a.yaml": "title: x\ntimeout: 1.5s\n",
+		"b.json": `{"name": "y"}`,
+	})
+
+	status, got, stderr := runCommand(t, "", "convert", "-I", dir, "-proto", "conf/conf.proto", "-type", "conf.Config", filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.json"))
+	if status != 0 {
+		t.Fatalf("run failed: %d: %s", status, stderr)
+	}
+	if diff := cmp.Diff("name: x\ntimeout: 1.500s\n---\nname: y\n", got); diff != "" {
+		t.Errorf("run: +got, -want:\n%s", diff)
+	}
+}
+
+func TestConvertComments(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"conf.proto": `syntax = "proto3";
+package conf;
+message Config {
+  // The name of the service.
+  string name = 1;
+  int32 port = 2;  // Where to listen.
+}
+`,
+	})
+
+	status, got, stderr := runCommand(t, `{"name": "x", "port": 80}`, "convert", "-I", dir, "-proto", "conf.proto", "-type", "conf.Config", "-from", "json", "-comments")
+	if status != 0 {
+		t.Fatalf("run failed: %d: %s", status, stderr)
+	}
+	want := "# The name of the service.\nname: x\nport: 80 # Where to listen.\n"
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("run: +got, -want:\n%s", diff)
+	}
+}
+
+func TestConvertOutput(t *testing.T) {
+	ds := writeDescriptorSet(t)
+	dir := writeFiles(t, map[string]string{
+		"a.yaml":   "anint32: 1\n",
+		"bad.yaml": "nosuchfield: 2\n",
+	})
+	a := filepath.Join(dir, "a.yaml")
+
+	status, _, _ := runCommand(t, "", "convert", "-d", ds, "-type", "protoyaml.test.Message", "-o", a, a, filepath.Join(dir, "bad.yaml"))
+	if status != 1 {
+		t.Fatalf("run: got status %d, want 1", status)
+	}
+	bs, err := os.ReadFile(a)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if diff := cmp.Diff("anint32: 1\n", string(bs)); diff != "" {
+		t.Errorf("run changed the output on error: +got, -want:\n%s", diff)
+	}
+
+	status, _, stderr := runCommand(t, "", "convert", "-d", ds, "-type", "protoyaml.test.Message", "-to", "json", "-o", a, a)
+	if status != 0 {
+		t.Fatalf("run failed: %d: %s", status, stderr)
+	}
+	if bs, err = os.ReadFile(a); err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if diff := cmp.Diff("{\"anint32\":1}\n", compactJSON(t, string(bs))); diff != "" {
+		t.Errorf("run: +got, -want:\n%s", diff)
+	}
+}
+
+func TestConvertError(t *testing.T) {
+	ds := writeDescriptorSet(t)
+
+	tsts := []struct {
+		Name string
+		Args []string
+		In   string
+		Want string
+	}{
+		{"noSchema", []string{"convert"}, "", "protoyaml convert: no schema: use -d or -proto\n"},
+		{"unknownType", []string{"convert", "-d", ds, "-type", "nosuch.Message"}, "", "protoyaml convert: unknown message nosuch.Message: proto: not found\n"},
+		{"noType", []string{"convert", "-d", ds, "-from", "json"}, "{}", "protoyaml convert: a message type is required for json input\n"},
+		{"badYAML", []string{"convert", "-d", ds, "-type", "protoyaml.test.Message"}, "anint32: 1\nnosuchfield: 2\n", "protoyaml convert: protoyaml: <stdin>:2:1: unknown field: protoyaml.test.Message.nosuchfield\n"},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Name, func(t *testing.T) {
+			status, _, stderr := runCommand(t, tst.In, tst.Args...)
+			if status != 1 {
+				t.Errorf("run: got status %d, want 1", status)
+			}
+			if diff := cmp.Diff(tst.Want, stderr); diff != "" {
+				t.Errorf("run: +got, -want:\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/tommie/protoyaml-go"
+)
+
+// runDiff compares the messages in two files, document by document,
+// see protoyaml.Diff. The exit status is 1 if they differ.
+func runDiff(env *cmdEnv, args []string) error {
+	fs := newFlagSet(env, "diff", "old-file new-file")
+	var sf schemaFlags
+	sf.register(fs)
+	typeName := fs.String("type", "", "the full `name` of the message (optional for YAML input with @type)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return fmt.Errorf("want two files to compare")
+	}
+
+	s, err := sf.load()
+	if err != nil {
+		return err
+	}
+	var mt protoreflect.MessageType
+	if *typeName != "" {
+		if mt, err = s.messageType(*typeName); err != nil {
+			return err
+		}
+	}
+
+	olds, err := readMessages(env, s, fs.Arg(0), mt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", displayName(fs.Arg(0)), err)
+	}
+	news, err := readMessages(env, s, fs.Arg(1), mt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", displayName(fs.Arg(1)), err)
+	}
+
+	var changed bool
+	multi := len(olds) > 1 || len(news) > 1
+	for i := 0; i < len(olds) || i < len(news); i++ {
+		var lines []string
+		switch {
+		case i >= len(olds):
+			lines = []string{"+ (document added)"}
+		case i >= len(news):
+			lines = []string{"- (document removed)"}
+		default:
+			cs, err := protoyaml.Diff(olds[i], news[i])
+			if err != nil {
+				return err
+			}
+			for _, c := range cs {
+				lines = append(lines, c.String())
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		changed = true
+
+		if multi {
+			fmt.Fprintf(env.stdout, "document %d:\n", i+1)
+		}
+		for _, line := range lines {
+			if _, err := fmt.Fprintln(env.stdout, line); err != nil {
+				return err
+			}
+		}
+	}
+	if changed {
+		return errFailed
+	}
+	return nil
+}
+
+// readMessages reads all messages in a file, in the format given by
+// its extension, or YAML.
+func readMessages(env *cmdEnv, s *schema, name string, mt protoreflect.MessageType) ([]proto.Message, error) {
+	r, err := openInput(env, name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	mr, err := newMessageReader(s, r, name, formatFromName(name, formatYAML), mt, false)
+	if err != nil {
+		return nil, err
+	}
+	var ms []proto.Message
+	for {
+		m, err := mr.Next()
+		if err == io.EOF {
+			return ms, nil
+		} else if err != nil {
+			return nil, err
+		}
+		ms = append(ms, m)
+	}
+}
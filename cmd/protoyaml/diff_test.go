@@ -0,0 +1,36 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiff(t *testing.T) {
+	ds := writeDescriptorSet(t)
+	dir := writeFiles(t, map[string]string{
+		"old.yaml":  "anenum: ONE # A comment.\nastring_int32_map: {a: 1, b: 2}\nanint32: 1\n",
+		"same.json": `{"anenum": 1, "astring_int32_map": {"b": 2, "a": 1}, "anint32": 1}`,
+		"new.yaml":  "anenum: 1\nastring_int32_map: {b: 3}\nanint32: 1\n---\nastring: x\n",
+	})
+
+	status, got, stderr := runCommand(t, "", "diff", "-d", ds, "-type", "protoyaml.test.Message", filepath.Join(dir, "old.yaml"), filepath.Join(dir, "same.json"))
+	if status != 0 || got != "" {
+		t.Errorf("run: got %d, %q, %q, want no differences", status, got, stderr)
+	}
+
+	status, got, stderr = runCommand(t, "", "diff", "-d", ds, "-type", "protoyaml.test.Message", filepath.Join(dir, "old.yaml"), filepath.Join(dir, "new.yaml"))
+	if status != 1 {
+		t.Fatalf("run: got status %d, want 1: %s", status, stderr)
+	}
+	want := `document 1:
+- astring_int32_map["a"]: 1
+~ astring_int32_map["b"]: 2 -> 3
+document 2:
++ (document added)
+`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("run: +got, -want:\n%s", diff)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/tommie/protoyaml-go"
+)
+
+// runFmt rewrites YAML files into canonical form, see
+// protoyaml.Formatter. With -check, files that are not formatted are
+// listed, and the exit status is 1.
+func runFmt(env *cmdEnv, args []string) error {
+	fs := newFlagSet(env, "fmt", "[file|directory...]")
+	var sf schemaFlags
+	sf.register(fs)
+	typeName := fs.String("type", "", "the full `name` of the message, for files not matched by -map")
+	var maps stringList
+	fs.Var(&maps, "map", "a `glob=name` pair selecting the message for matching files (repeatable)")
+	write := fs.Bool("w", false, "write the result back to each file, instead of stdout")
+	check := fs.Bool("check", false, "only list the files that are not formatted")
+	numberOrder := fs.Bool("number-order", false, "order keys by field number, instead of declaration order")
+	goDurations := fs.Bool("go-durations", false, "write durations like Go, e.g. 1m30s, instead of like protojson")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *write && *check {
+		return fmt.Errorf("cannot use both -w and -check")
+	}
+
+	s, err := sf.load()
+	if err != nil {
+		return err
+	}
+	tm, err := newTypeMap(s, maps, *typeName)
+	if err != nil {
+		return err
+	}
+
+	names := []string{"-"}
+	if fs.NArg() > 0 {
+		if names, err = yamlFiles(fs.Args()); err != nil {
+			return err
+		}
+	}
+
+	f := protoyaml.NewFormatter()
+	f.FieldNumberOrder(*numberOrder)
+	f.GoDurations(*goDurations)
+
+	var unformatted bool
+	for _, name := range names {
+		if *write && name == "-" {
+			return fmt.Errorf("cannot use -w with stdin")
+		}
+		mt, err := tm.lookup(name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", displayName(name), err)
+		}
+		in, out, err := formatFile(env, s, f, name, mt)
+		if err != nil {
+			return fmt.Errorf("%s: %w", displayName(name), err)
+		}
+		changed := !bytes.Equal(in, out)
+
+		switch {
+		case *check:
+			if changed {
+				unformatted = true
+				if _, err := fmt.Fprintln(env.stdout, displayName(name)); err != nil {
+					return err
+				}
+			}
+		case *write:
+			if changed {
+				if err := os.WriteFile(name, out, 0666); err != nil {
+					return err
+				}
+			}
+		default:
+			if _, err := env.stdout.Write(out); err != nil {
+				return err
+			}
+		}
+	}
+	if unformatted {
+		return errFailed
+	}
+	return nil
+}
+
+// formatFile returns the contents of a file, and the formatted
+// contents. Files that don't decode are not formatted, since the
+// formatter can't know what they mean.
+func formatFile(env *cmdEnv, s *schema, f *protoyaml.Formatter, name string, mt protoreflect.MessageType) ([]byte, []byte, error) {
+	r, err := openInput(env, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer r.Close()
+	in, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	d := protoyaml.NewDecoder(bytes.NewReader(in))
+	d.FileName(displayName(name))
+	d.MessageTypeResolver(s.types)
+	d.ExtensionTypeResolver(s.types)
+	d.AllowPartial(true)
+	for {
+		if err := d.Decode(mt.New()); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	out, err := f.Format(in, mt.Descriptor())
+	if err != nil {
+		return nil, nil, err
+	}
+	return in, out, nil
+}
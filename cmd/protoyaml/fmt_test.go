@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFmt(t *testing.T) {
+	ds := writeDescriptorSet(t)
+	dir := writeFiles(t, map[string]string{
+		"a.yaml":     "anint32: 1 # One.\nanenum: 2\nabool: true\n",
+		"b.yaml":     "abool: true\nanint32: 1\n",
+		"c.yaml":     "# Nothing yet.\n",
+		"d.yaml":     "amessage:\n  abool: true\n",
+		"bad/c.yaml": "anint32: x\n",
+	})
+	a, b := filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml")
+
+	status, got, stderr := runCommand(t, "", "fmt", "-d", ds, "-type", "protoyaml.test.Message", "-check", a, b, filepath.Join(dir, "c.yaml"), filepath.Join(dir, "d.yaml"))
+	if status != 1 {
+		t.Fatalf("run: got status %d, want 1: %s", status, stderr)
+	}
+	if diff := cmp.Diff(a+"\n", got); diff != "" {
+		t.Errorf("run: +got, -want:\n%s", diff)
+	}
+
+	status, _, stderr = runCommand(t, "", "fmt", "-d", ds, "-type", "protoyaml.test.Message", "-w", a)
+	if status != 0 {
+		t.Fatalf("run failed: %d: %s", status, stderr)
+	}
+	bs, err := os.ReadFile(a)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if diff := cmp.Diff("abool: true\nanint32: 1 # One.\nanenum: TWO\n", string(bs)); diff != "" {
+		t.Errorf("run: +got, -want:\n%s", diff)
+	}
+
+	status, _, stderr = runCommand(t, "", "fmt", "-d", ds, "-type", "protoyaml.test.Message", "-check", filepath.Join(dir, "bad"))
+	if status != 1 || !strings.Contains(stderr, "c.yaml:1:10: cannot unmarshal") {
+		t.Errorf("run: got %d, %q, want a decoding error", status, stderr)
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/tommie/protoyaml-go"
+)
+
+// Message formats.
+const (
+	formatYAML   = "yaml"
+	formatJSON   = "json"
+	formatBinary = "binary"
+	formatText   = "text"
+)
+
+// formatFromName guesses the format of a file from its extension.
+// Returns def for unknown extensions, and stdin.
+func formatFromName(name, def string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		return formatYAML
+	case ".json":
+		return formatJSON
+	case ".binpb", ".pb", ".bin":
+		return formatBinary
+	case ".txtpb", ".textproto", ".txt":
+		return formatText
+	default:
+		return def
+	}
+}
+
+// A messageReader reads a stream of messages. Next returns io.EOF
+// when there are no more messages.
+type messageReader interface {
+	Next() (proto.Message, error)
+}
+
+// newMessageReader returns a reader for the given format. The name is
+// used in YAML error positions. The message type mt may be nil for
+// YAML, where each document then selects its type with "@type".
+// Binary streams are read as size-delimited messages if delimited is
+// true, and text input is a single message.
+func newMessageReader(s *schema, r io.Reader, name, format string, mt protoreflect.MessageType, delimited bool) (messageReader, error) {
+	if mt == nil && format != formatYAML {
+		return nil, fmt.Errorf("a message type is required for %s input", format)
+	}
+
+	switch format {
+	case formatYAML:
+		d := protoyaml.NewDecoder(r)
+		d.FileName(displayName(name))
+		d.MessageTypeResolver(s.types)
+		d.ExtensionTypeResolver(s.types)
+		return &yamlReader{d: d, mt: mt}, nil
+
+	case formatJSON:
+		return &jsonReader{
+			d:    json.NewDecoder(r),
+			mt:   mt,
+			opts: protojson.UnmarshalOptions{Resolver: s.types},
+		}, nil
+
+	case formatBinary:
+		if delimited {
+			return &delimitedReader{
+				r:    bufio.NewReader(r),
+				mt:   mt,
+				opts: protodelim.UnmarshalOptions{MaxSize: -1, UnmarshalOptions: proto.UnmarshalOptions{Resolver: s.types}},
+			}, nil
+		}
+		return &wholeReader{r: r, mt: mt, unmarshal: proto.UnmarshalOptions{Resolver: s.types}.Unmarshal}, nil
+
+	case formatText:
+		return &wholeReader{r: r, mt: mt, unmarshal: prototext.UnmarshalOptions{Resolver: s.types}.Unmarshal}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+type yamlReader struct {
+	d  *protoyaml.Decoder
+	mt protoreflect.MessageType
+}
+
+func (r *yamlReader) Next() (proto.Message, error) {
+	if r.mt == nil {
+		return r.d.DecodeNew()
+	}
+	m := r.mt.New().Interface()
+	if err := r.d.Decode(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// A jsonReader reads a stream of JSON values, e.g. one per line.
+type jsonReader struct {
+	d    *json.Decoder
+	mt   protoreflect.MessageType
+	opts protojson.UnmarshalOptions
+}
+
+func (r *jsonReader) Next() (proto.Message, error) {
+	var raw json.RawMessage
+	if err := r.d.Decode(&raw); err != nil {
+		return nil, err
+	}
+	m := r.mt.New().Interface()
+	if err := r.opts.Unmarshal(raw, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+type delimitedReader struct {
+	r    *bufio.Reader
+	mt   protoreflect.MessageType
+	opts protodelim.UnmarshalOptions
+}
+
+func (r *delimitedReader) Next() (proto.Message, error) {
+	m := r.mt.New().Interface()
+	if err := r.opts.UnmarshalFrom(r.r, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// A wholeReader reads all input as a single message.
+type wholeReader struct {
+	r         io.Reader
+	mt        protoreflect.MessageType
+	unmarshal func([]byte, proto.Message) error
+	done      bool
+}
+
+func (r *wholeReader) Next() (proto.Message, error) {
+	if r.done {
+		return nil, io.EOF
+	}
+	r.done = true
+
+	bs, err := io.ReadAll(r.r)
+	if err != nil {
+		return nil, err
+	}
+	m := r.mt.New().Interface()
+	if err := r.unmarshal(bs, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// A messageWriter writes a stream of messages.
+type messageWriter interface {
+	Write(proto.Message) error
+	Close() error
+}
+
+// newMessageWriter returns a writer for the given format. YAML
+// streams are separated by "---", and JSON values by newlines. Binary
+// streams are written as size-delimited messages if delimited is
+// true. Otherwise, binary and text output can only hold one message.
+func newMessageWriter(s *schema, w io.Writer, format string, delimited bool) (messageWriter, error) {
+	switch format {
+	case formatYAML:
+		e := protoyaml.NewEncoder(w)
+		e.MessageTypeResolver(s.types)
+		return yamlWriter{e}, nil
+
+	case formatJSON:
+		opts := protojson.MarshalOptions{Resolver: s.types, Multiline: true, Indent: "  "}
+		return &funcWriter{w: w, marshal: opts.Marshal, sep: "\n", multi: true}, nil
+
+	case formatBinary:
+		if delimited {
+			return &funcWriter{w: w, marshal: delimitedMarshal, multi: true}, nil
+		}
+		return &funcWriter{w: w, marshal: proto.MarshalOptions{Deterministic: true}.Marshal}, nil
+
+	case formatText:
+		opts := prototext.MarshalOptions{Resolver: s.types, Multiline: true, Indent: "  "}
+		return &funcWriter{w: w, marshal: opts.Marshal}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+type yamlWriter struct {
+	e *protoyaml.Encoder
+}
+
+func (w yamlWriter) Write(m proto.Message) error { return w.e.Encode(m) }
+func (w yamlWriter) Close() error                { return w.e.Close() }
+
+// A funcWriter writes messages using a marshal function, with an
+// optional separator after each message.
+type funcWriter struct {
+	w       io.Writer
+	marshal func(proto.Message) ([]byte, error)
+	sep     string
+	multi   bool
+	n       int
+}
+
+func (w *funcWriter) Write(m proto.Message) error {
+	if w.n > 0 && !w.multi {
+		return fmt.Errorf("the output format can only hold one message (use -delimited for binary)")
+	}
+	w.n++
+
+	bs, err := w.marshal(m)
+	if err != nil {
+		return err
+	}
+	if _, err := w.w.Write(bs); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w.w, w.sep)
+	return err
+}
+
+func (w *funcWriter) Close() error { return nil }
+
+// delimitedMarshal returns a size-delimited message.
+func delimitedMarshal(m proto.Message) ([]byte, error) {
+	bs, err := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return append(protowire.AppendVarint(nil, uint64(len(bs))), bs...), nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tommie/protoyaml-go"
+)
+
+// runGet writes the value at a field path in each document, see
+// protoyaml.GetPathNode. If all values are scalars, they are written
+// as plain text lines, and otherwise as a stream of YAML documents.
+func runGet(env *cmdEnv, args []string) error {
+	fs := newFlagSet(env, "get", "path [file...]")
+	var sf schemaFlags
+	sf.register(fs)
+	typeName := fs.String("type", "", "the full `name` of the message")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no field path")
+	}
+
+	s, err := sf.load()
+	if err != nil {
+		return err
+	}
+	mt, err := s.messageType(*typeName)
+	if err != nil {
+		return err
+	}
+
+	var ns []*yaml.Node
+	scalars := true
+	for _, name := range inputNames(fs.Args()[1:]) {
+		fns, err := getFile(env, s, name, mt, fs.Arg(0))
+		if err != nil {
+			return fmt.Errorf("%s: %w", displayName(name), err)
+		}
+		for _, n := range fns {
+			scalars = scalars && n.Kind == yaml.ScalarNode
+		}
+		ns = append(ns, fns...)
+	}
+
+	if scalars {
+		for _, n := range ns {
+			if _, err := fmt.Fprintln(env.stdout, n.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	ye := yaml.NewEncoder(env.stdout)
+	for _, n := range ns {
+		if err := ye.Encode(n); err != nil {
+			return err
+		}
+	}
+	return ye.Close()
+}
+
+// getFile returns the value at path in each document of a file.
+func getFile(env *cmdEnv, s *schema, name string, mt protoreflect.MessageType, path string) ([]*yaml.Node, error) {
+	r, err := openInput(env, name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	d := protoyaml.NewDecoder(r)
+	d.MessageTypeResolver(s.types)
+	d.ExtensionTypeResolver(s.types)
+	d.AllowPartial(true)
+	var ns []*yaml.Node
+	for {
+		m := mt.New().Interface()
+		if err := d.Decode(m); err == io.EOF {
+			return ns, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		n, err := d.GetPathNode(m, path)
+		if err != nil {
+			return nil, err
+		}
+		ns = append(ns, n)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGet(t *testing.T) {
+	ds := writeDescriptorSet(t)
+
+	tsts := []struct {
+		Name string
+		Path string
+		Want string
+	}{
+		{"scalar", "amessage.anint32", "42\n0\n"},
+		{"map", "astring_int32_map", "a: 1\n---\n{}\n"},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Name, func(t *testing.T) {
+			in := "amessage: {anint32: 42}\nastring_int32_map: {a: 1}\n---\nastring: x\n"
+			status, got, stderr := runCommand(t, in, "get", "-d", ds, "-type", "protoyaml.test.Message", tst.Path)
+			if status != 0 {
+				t.Fatalf("run failed: %d: %s", status, stderr)
+			}
+			if diff := cmp.Diff(tst.Want, got); diff != "" {
+				t.Errorf("run: +got, -want:\n%s", diff)
+			}
+		})
+	}
+}
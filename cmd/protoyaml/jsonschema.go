@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/tommie/protoyaml-go"
+)
+
+// runJSONSchema writes the JSON Schema of a message, see
+// protoyaml.JSONSchema.
+func runJSONSchema(env *cmdEnv, args []string) error {
+	fs := newFlagSet(env, "jsonschema", "")
+	var sf schemaFlags
+	sf.register(fs)
+	typeName := fs.String("type", "", "the full `name` of the message")
+	out := fs.String("o", "-", "the output `file`")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	s, err := sf.load()
+	if err != nil {
+		return err
+	}
+	mt, err := s.messageType(*typeName)
+	if err != nil {
+		return err
+	}
+
+	bs, err := json.MarshalIndent(protoyaml.JSONSchema(mt.Descriptor()), "", "  ")
+	if err != nil {
+		return err
+	}
+	bs = append(bs, '\n')
+	if *out != "-" {
+		return os.WriteFile(*out, bs, 0666)
+	}
+	_, err = env.stdout.Write(bs)
+	return err
+}
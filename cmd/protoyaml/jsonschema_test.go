@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONSchema(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"conf.proto": `syntax = "proto3";
+package conf;
+// A config.
+message Config {
+  string name = 1;
+}
+`,
+	})
+
+	status, got, stderr := runCommand(t, "", "jsonschema", "-I", dir, "-proto", "conf.proto", "-type", "conf.Config")
+	if status != 0 {
+		t.Fatalf("run failed: %d: %s", status, stderr)
+	}
+
+	var s struct {
+		Ref  string `json:"$ref"`
+		Defs map[string]struct {
+			Description string `json:"description"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal([]byte(got), &s); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if want := "#/$defs/conf.Config"; s.Ref != want {
+		t.Errorf("run: got $ref %q, want %q", s.Ref, want)
+	}
+	if got, want := s.Defs["conf.Config"].Description, "A config."; got != want {
+		t.Errorf("run: got description %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,143 @@
+// Command protoyaml works with YAML files holding Protobuf messages.
+//
+// Usage:
+//
+//	protoyaml <command> [flags] [file...]
+//
+// The schema is given as descriptor sets (-d), as produced by
+// `protoc --include_imports --descriptor_set_out`, or as .proto files
+// (-proto), which are compiled with a bundled parser. Files are read
+// relative to the import paths (-I). Input is read from stdin if no
+// file is given, or the file is "-".
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// A command is a subcommand of the tool.
+type command struct {
+	// run runs the command with the arguments after the command name.
+	run func(env *cmdEnv, args []string) error
+
+	// usage is a one-line description.
+	usage string
+}
+
+var commands = map[string]command{
+	"breaking":   {runBreaking, "check that YAML files mean the same with a new schema"},
+	"convert":    {runConvert, "convert messages between YAML, JSON, binary and text formats"},
+	"diff":       {runDiff, "compare the messages in two files, field by field"},
+	"fmt":        {runFmt, "rewrite YAML files into canonical form, or check that they are"},
+	"get":        {runGet, "print the value at a field path"},
+	"jsonschema": {runJSONSchema, "write a JSON Schema for editor support of YAML files"},
+	"rename":     {runRename, "replace field aliases with the current field names"},
+	"set":        {runSet, "set values at field paths, keeping comments and key order"},
+	"skeleton":   {runSkeleton, "write an example YAML document, with comments"},
+	"validate":   {runValidate, "check that YAML files decode, and report all problems"},
+}
+
+// A cmdEnv holds the standard streams of a command.
+type cmdEnv struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// errFailed is returned by commands that have already reported what
+// went wrong, but should exit with a non-zero status.
+var errFailed = errors.New("failed")
+
+func main() {
+	os.Exit(run(&cmdEnv{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}, os.Args[1:]))
+}
+
+// run runs the command line, and returns the exit status.
+func run(env *cmdEnv, args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		printUsage(env.stderr)
+		return 2
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(env.stderr, "protoyaml: unknown command %q\n", args[0])
+		printUsage(env.stderr)
+		return 2
+	}
+
+	if err := cmd.run(env, args[1:]); err == flag.ErrHelp {
+		return 2
+	} else if err == errFailed {
+		return 1
+	} else if err != nil {
+		fmt.Fprintf(env.stderr, "protoyaml %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "usage: protoyaml <command> [flags] [file...]\n\nCommands:\n")
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].usage)
+	}
+}
+
+// newFlagSet returns a flag set for a command, writing errors to the
+// command's stderr.
+func newFlagSet(env *cmdEnv, name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet("protoyaml "+name, flag.ContinueOnError)
+	fs.SetOutput(env.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(env.stderr, "usage: protoyaml %s [flags] %s\n\nFlags:\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// A stringList is a flag that can be given multiple times.
+type stringList []string
+
+func (l *stringList) String() string {
+	return fmt.Sprint(*l)
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// openInput opens a named input file, or stdin for "-".
+func openInput(env *cmdEnv, name string) (io.ReadCloser, error) {
+	if name == "-" {
+		return io.NopCloser(env.stdin), nil
+	}
+	return os.Open(name)
+}
+
+// displayName returns the name of an input file in messages.
+func displayName(name string) string {
+	if name == "-" {
+		return "<stdin>"
+	}
+	return name
+}
+
+// inputNames returns the input file names, defaulting to stdin.
+func inputNames(args []string) []string {
+	if len(args) == 0 {
+		return []string{"-"}
+	}
+	return args
+}
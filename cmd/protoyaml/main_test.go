@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/tommie/protoyaml-go/internal/testproto"
+)
+
+// runCommand runs the tool, and returns the exit status and output.
+// The protobuf module randomly uses non-breaking spaces in errors, to
+// discourage comparing them, so they are replaced by spaces.
+func runCommand(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	status := run(&cmdEnv{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr}, args)
+	return status, stdout.String(), strings.ReplaceAll(stderr.String(), "\u00a0", " ")
+}
+
+// compactJSON returns a stream of JSON values, one per line, without
+// insignificant whitespace. Multiline protojson output is randomized.
+func compactJSON(t *testing.T, s string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	d := json.NewDecoder(strings.NewReader(s))
+	for {
+		var raw json.RawMessage
+		if err := d.Decode(&raw); err == io.EOF {
+			return buf.String()
+		} else if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		if err := json.Compact(&buf, raw); err != nil {
+			t.Fatalf("Compact failed: %v", err)
+		}
+		buf.WriteByte('\n')
+	}
+}
+
+// writeDescriptorSet writes a descriptor set with the test protos to
+// a temporary file, and returns its name.
+func writeDescriptorSet(t *testing.T) string {
+	t.Helper()
+
+	return writeEditedDescriptorSet(t, func(*descriptorpb.FileDescriptorProto) {})
+}
+
+// writeEditedDescriptorSet is like writeDescriptorSet, but edit can
+// change each file first.
+func writeEditedDescriptorSet(t *testing.T, edit func(*descriptorpb.FileDescriptorProto)) string {
+	t.Helper()
+
+	var fds descriptorpb.FileDescriptorSet
+	seen := map[string]bool{}
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		for i := 0; i < fd.Imports().Len(); i++ {
+			add(fd.Imports().Get(i).FileDescriptor)
+		}
+		fdp := protodesc.ToFileDescriptorProto(fd)
+		edit(fdp)
+		fds.File = append(fds.File, fdp)
+	}
+	add(testproto.File_internal_testproto_test_proto)
+
+	bs, err := proto.Marshal(&fds)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	name := filepath.Join(t.TempDir(), "test.binpb")
+	if err := os.WriteFile(name, bs, 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	return name
+}
+
+// writeFiles writes files to a temporary directory, and returns its
+// name.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, data := range files {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+		if err := os.WriteFile(name, []byte(data), 0644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestRunUsage(t *testing.T) {
+	status, _, stderr := runCommand(t, "")
+	if status != 2 {
+		t.Errorf("run: got status %d, want 2", status)
+	}
+	if !strings.Contains(stderr, "convert") {
+		t.Errorf("run: got %q, want a list of commands", stderr)
+	}
+
+	status, _, stderr = runCommand(t, "", "nosuchcommand")
+	if status != 2 || !strings.Contains(stderr, `unknown command "nosuchcommand"`) {
+		t.Errorf("run: got %d, %q, want an unknown command error", status, stderr)
+	}
+}
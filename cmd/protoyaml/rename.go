@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/tommie/protoyaml-go"
+)
+
+// runRename replaces field aliases with the current field names, see
+// protoyaml.RenameAliases.
+func runRename(env *cmdEnv, args []string) error {
+	fs := newFlagSet(env, "rename", "[file...]")
+	var sf schemaFlags
+	sf.register(fs)
+	typeName := fs.String("type", "", "the full `name` of the message")
+	write := fs.Bool("w", false, "write the result back to each file, instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	s, err := sf.load()
+	if err != nil {
+		return err
+	}
+	mt, err := s.messageType(*typeName)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range inputNames(fs.Args()) {
+		if *write && name == "-" {
+			return fmt.Errorf("cannot use -w with stdin")
+		}
+		bs, n, err := renameFile(env, name, func(doc *yaml.Node) int {
+			return protoyaml.RenameAliases(doc, mt.Descriptor())
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", displayName(name), err)
+		}
+		if !*write {
+			if _, err := env.stdout.Write(bs); err != nil {
+				return err
+			}
+		} else if n > 0 {
+			if err := os.WriteFile(name, bs, 0666); err != nil {
+				return err
+			}
+			fmt.Fprintf(env.stderr, "%s: renamed %d keys\n", name, n)
+		}
+	}
+	return nil
+}
+
+// renameFile applies f to each document in a YAML file, and returns
+// the updated text, and the sum of what f returned. The indentation of
+// the first document is kept.
+func renameFile(env *cmdEnv, name string, f func(*yaml.Node) int) ([]byte, int, error) {
+	r, err := openInput(env, name)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer r.Close()
+
+	var buf bytes.Buffer
+	ye := yaml.NewEncoder(&buf)
+	yd := yaml.NewDecoder(r)
+	var count int
+	for first := true; ; first = false {
+		var doc yaml.Node
+		if err := yd.Decode(&doc); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, 0, err
+		}
+		if first {
+			// The indentation can only be set before the first
+			// document.
+			ye.SetIndent(protoyaml.DocumentIndent(&doc))
+		}
+		count += f(&doc)
+		if err := ye.Encode(&doc); err != nil {
+			return nil, 0, err
+		}
+	}
+	if err := ye.Close(); err != nil {
+		return nil, 0, err
+	}
+	return buf.Bytes(), count, nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRename(t *testing.T) {
+	ds := writeDescriptorSet(t)
+	dir := writeFiles(t, map[string]string{
+		"a.yaml": "# Config.\nanold: a # Old.\namessage:\n  anold: c\n---\namessage: {anolder: b}\n",
+	})
+	name := filepath.Join(dir, "a.yaml")
+
+	status, _, stderr := runCommand(t, "", "rename", "-d", ds, "-type", "protoyaml.test.Message", "-w", name)
+	if status != 0 {
+		t.Fatalf("run failed: %d: %s", status, stderr)
+	}
+	if want := name + ": renamed 3 keys\n"; stderr != want {
+		t.Errorf("run: got %q, want %q", stderr, want)
+	}
+
+	bs, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if diff := cmp.Diff("# Config.\narenamed: a # Old.\namessage:\n  arenamed: c\n---\namessage: {arenamed: b}\n", string(bs)); diff != "" {
+		t.Errorf("run: +got, -want:\n%s", diff)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/tommie/protoyaml-go/protoyamlpb"
+)
+
+// schemaFlags are the flags selecting where descriptors are loaded
+// from.
+type schemaFlags struct {
+	descSets    stringList
+	protos      stringList
+	importPaths stringList
+}
+
+func (sf *schemaFlags) register(fs *flag.FlagSet) {
+	fs.Var(&sf.descSets, "d", "a binary FileDescriptorSet `file` (repeatable)")
+	fs.Var(&sf.protos, "proto", "a .proto `file` to compile, relative to an import path (repeatable)")
+	fs.Var(&sf.importPaths, "I", "an import path `directory` for -proto (repeatable, default \".\")")
+}
+
+// A schema is a set of loaded descriptors.
+type schema struct {
+	files *protoregistry.Files
+	types *dynamicpb.Types
+}
+
+// load reads all descriptor sets and .proto files.
+func (sf *schemaFlags) load() (*schema, error) {
+	if len(sf.descSets) == 0 && len(sf.protos) == 0 {
+		return nil, fmt.Errorf("no schema: use -d or -proto")
+	}
+
+	files := &protoregistry.Files{}
+	for _, name := range sf.descSets {
+		if err := loadDescriptorSet(files, name); err != nil {
+			return nil, err
+		}
+	}
+	if len(sf.protos) > 0 {
+		if err := compileProtos(files, sf.importPaths, sf.protos); err != nil {
+			return nil, err
+		}
+	}
+	return &schema{files: files, types: dynamicpb.NewTypes(files)}, nil
+}
+
+// loadDescriptorSet registers all files in a descriptor set file.
+func loadDescriptorSet(files *protoregistry.Files, name string) error {
+	bs, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	var fds descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(bs, &fds); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	set, err := protodesc.NewFiles(&fds)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	var rerr error
+	set.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		rerr = registerFile(files, fd)
+		return rerr == nil
+	})
+	return rerr
+}
+
+// compileProtos compiles .proto files, and registers them and their
+// dependencies. The protoyaml options are always available as
+// "protoyamlpb/options.proto".
+func compileProtos(files *protoregistry.Files, importPaths, names []string) error {
+	if len(importPaths) == 0 {
+		importPaths = []string{"."}
+	}
+	c := protocompile.Compiler{
+		Resolver: protocompile.CompositeResolver{
+			protocompile.ResolverFunc(func(path string) (protocompile.SearchResult, error) {
+				if path == protoyamlpb.File_protoyamlpb_options_proto.Path() {
+					return protocompile.SearchResult{Desc: protoyamlpb.File_protoyamlpb_options_proto}, nil
+				}
+				return protocompile.SearchResult{}, os.ErrNotExist
+			}),
+			protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: importPaths}),
+		},
+		SourceInfoMode: protocompile.SourceInfoStandard,
+	}
+	res, err := c.Compile(context.Background(), names...)
+	if err != nil {
+		return err
+	}
+	for _, fd := range res {
+		if err := registerFile(files, fd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registerFile registers a file and its imports, unless already
+// registered.
+func registerFile(files *protoregistry.Files, fd protoreflect.FileDescriptor) error {
+	if _, err := files.FindFileByPath(fd.Path()); err == nil {
+		return nil
+	}
+	for i := 0; i < fd.Imports().Len(); i++ {
+		if err := registerFile(files, fd.Imports().Get(i).FileDescriptor); err != nil {
+			return err
+		}
+	}
+	return files.RegisterFile(fd)
+}
+
+// messageType returns the message type with the given full name.
+func (s *schema) messageType(name string) (protoreflect.MessageType, error) {
+	mt, err := s.types.FindMessageByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("unknown message %s: %w", name, err)
+	}
+	return mt, nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/tommie/protoyaml-go"
+)
+
+// runSet sets values at field paths in each document, keeping the
+// rest of the file like protoyaml.Document does, see
+// protoyaml.SetPath. Arguments
+// containing "=" are assignments, and the rest are files.
+func runSet(env *cmdEnv, args []string) error {
+	fs := newFlagSet(env, "set", "path=value... [file...]")
+	var sf schemaFlags
+	sf.register(fs)
+	typeName := fs.String("type", "", "the full `name` of the message")
+	write := fs.Bool("w", false, "write the result back to each file, instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var sets [][2]string
+	var files []string
+	for _, arg := range fs.Args() {
+		if path, value, ok := splitAssignment(arg); ok {
+			sets = append(sets, [2]string{path, value})
+		} else {
+			files = append(files, arg)
+		}
+	}
+	if len(sets) == 0 {
+		return fmt.Errorf("no path=value assignments")
+	}
+
+	s, err := sf.load()
+	if err != nil {
+		return err
+	}
+	mt, err := s.messageType(*typeName)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range inputNames(files) {
+		if *write && name == "-" {
+			return fmt.Errorf("cannot use -w with stdin")
+		}
+		bs, err := setFile(env, s, name, mt, sets)
+		if err != nil {
+			return fmt.Errorf("%s: %w", displayName(name), err)
+		}
+		if *write {
+			err = os.WriteFile(name, bs, 0666)
+		} else {
+			_, err = env.stdout.Write(bs)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitAssignment splits a path=value argument at the first "=" that
+// is not inside a map key.
+func splitAssignment(arg string) (string, string, bool) {
+	var depth int
+	var quoted bool
+	for i := 0; i < len(arg); i++ {
+		switch c := arg[i]; {
+		case quoted && c == '\\':
+			i++
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == '=' && depth == 0:
+			return arg[:i], arg[i+1:], true
+		}
+	}
+	return "", "", false
+}
+
+// setFile applies the assignments to each document in a file, and
+// returns the updated text.
+func setFile(env *cmdEnv, s *schema, name string, mt protoreflect.MessageType, sets [][2]string) ([]byte, error) {
+	r, err := openInput(env, name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	d := protoyaml.NewDecoder(r)
+	d.MessageTypeResolver(s.types)
+	d.ExtensionTypeResolver(s.types)
+	d.AllowPartial(true)
+
+	var buf bytes.Buffer
+	e := protoyaml.NewEncoder(&buf)
+	e.MessageTypeResolver(s.types)
+	for {
+		m := mt.New().Interface()
+		doc, err := d.DecodeDocument(m)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		for _, set := range sets {
+			if err := d.SetPath(m, set[0], set[1]); err != nil {
+				return nil, err
+			}
+		}
+		if err := e.EncodeDocument(doc, m); err != nil {
+			return nil, err
+		}
+	}
+	if err := e.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSet(t *testing.T) {
+	ds := writeDescriptorSet(t)
+	dir := writeFiles(t, map[string]string{
+		"a.yaml": "# Config.\namessage:\n    anint32: 1 # Version.\nastring: 'x'\n",
+	})
+	name := filepath.Join(dir, "a.yaml")
+
+	status, _, stderr := runCommand(t, "", "set", "-d", ds, "-type", "protoyaml.test.Message", "-w", name, "amessage.anint32=2", `astring_int32_map["a=b"]=3`)
+	if status != 0 {
+		t.Fatalf("run failed: %d: %s", status, stderr)
+	}
+
+	bs, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	want := `# Config.
+amessage:
+    anint32: 2 # Version.
+astring: 'x'
+astring_int32_map:
+    a=b: 3
+`
+	if diff := cmp.Diff(want, string(bs)); diff != "" {
+		t.Errorf("run: +got, -want:\n%s", diff)
+	}
+}
+
+func TestSetError(t *testing.T) {
+	ds := writeDescriptorSet(t)
+
+	status, _, stderr := runCommand(t, "astring: x\n", "set", "-d", ds, "-type", "protoyaml.test.Message", "anint32=x")
+	if status != 1 || !strings.Contains(stderr, "<stdin>: protoyaml: anint32: cannot unmarshal") {
+		t.Errorf("run: got %d, %q, want a conversion error", status, stderr)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+
+	"github.com/tommie/protoyaml-go"
+)
+
+// runSkeleton writes an example YAML document for a message, see
+// protoyaml.Skeleton.
+func runSkeleton(env *cmdEnv, args []string) error {
+	fs := newFlagSet(env, "skeleton", "")
+	var sf schemaFlags
+	sf.register(fs)
+	typeName := fs.String("type", "", "the full `name` of the message")
+	depth := fs.Int("depth", 2, "the number of nested message `levels` to expand")
+	out := fs.String("o", "-", "the output `file`")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	s, err := sf.load()
+	if err != nil {
+		return err
+	}
+	mt, err := s.messageType(*typeName)
+	if err != nil {
+		return err
+	}
+
+	bs, err := protoyaml.Skeleton(mt.Descriptor(), *depth)
+	if err != nil {
+		return err
+	}
+	if *out != "-" {
+		return os.WriteFile(*out, bs, 0666)
+	}
+	_, err = env.stdout.Write(bs)
+	return err
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSkeleton(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"conf.proto": `syntax = "proto3";
+package conf;
+message Config {
+  // The name of the service.
+  string name = 1;
+  Backend backend = 2;
+}
+message Backend {
+  string address = 1;
+}
+`,
+	})
+
+	status, got, stderr := runCommand(t, "", "skeleton", "-I", dir, "-proto", "conf.proto", "-type", "conf.Config", "-depth", "0")
+	if status != 0 {
+		t.Fatalf("run failed: %d: %s", status, stderr)
+	}
+
+	want := `# The name of the service.
+name: ""
+backend: {}
+`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("run: +got, -want:\n%s", diff)
+	}
+}
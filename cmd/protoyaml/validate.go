@@ -0,0 +1,304 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tommie/protoyaml-go"
+)
+
+// runValidate decodes YAML files, and reports all problems found.
+func runValidate(env *cmdEnv, args []string) error {
+	fs := newFlagSet(env, "validate", "file|directory...")
+	var sf schemaFlags
+	sf.register(fs)
+	typeName := fs.String("type", "", "the full `name` of the message, for files not matched by -map")
+	var maps stringList
+	fs.Var(&maps, "map", "a `glob=name` pair selecting the message for matching files (repeatable)")
+	format := fs.String("format", "text", "the output `format`: text, json or github")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no files to validate")
+	}
+
+	s, err := sf.load()
+	if err != nil {
+		return err
+	}
+	tm, err := newTypeMap(s, maps, *typeName)
+	if err != nil {
+		return err
+	}
+
+	names, err := yamlFiles(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	var diags []diagnostic
+	for _, name := range names {
+		mt, err := tm.lookup(name)
+		if err != nil {
+			diags = append(diags, diagnostic{File: name, Severity: severityError, Message: err.Error()})
+			continue
+		}
+		diags = append(diags, validateFile(env, s, name, mt)...)
+	}
+
+	if err := writeDiagnostics(env.stdout, *format, diags); err != nil {
+		return err
+	}
+	for _, diag := range diags {
+		if diag.Severity == severityError {
+			return errFailed
+		}
+	}
+	return nil
+}
+
+// A typeMap selects the message type of a file.
+type typeMap struct {
+	globs []string
+	types []protoreflect.MessageType
+	def   protoreflect.MessageType
+}
+
+// newTypeMap parses glob=name pairs. The default type is optional.
+func newTypeMap(s *schema, pairs []string, def string) (*typeMap, error) {
+	var tm typeMap
+	for _, pair := range pairs {
+		glob, name, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid -map %q: want glob=name", pair)
+		}
+		if _, err := path.Match(glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid -map %q: %w", pair, err)
+		}
+		mt, err := s.messageType(name)
+		if err != nil {
+			return nil, err
+		}
+		tm.globs = append(tm.globs, glob)
+		tm.types = append(tm.types, mt)
+	}
+	if def != "" {
+		mt, err := s.messageType(def)
+		if err != nil {
+			return nil, err
+		}
+		tm.def = mt
+	}
+	if tm.def == nil && len(tm.types) == 0 {
+		return nil, fmt.Errorf("no message type: use -type or -map")
+	}
+	return &tm, nil
+}
+
+// lookup returns the type of the first glob matching the file name, or
+// its base name.
+func (tm *typeMap) lookup(name string) (protoreflect.MessageType, error) {
+	slashed := filepath.ToSlash(name)
+	for i, glob := range tm.globs {
+		if ok, _ := path.Match(glob, slashed); ok {
+			return tm.types[i], nil
+		}
+		if ok, _ := path.Match(glob, path.Base(slashed)); ok {
+			return tm.types[i], nil
+		}
+	}
+	if tm.def == nil {
+		return nil, fmt.Errorf("no -map matches the file, and there is no -type")
+	}
+	return tm.def, nil
+}
+
+// yamlFiles returns the named files, and all YAML files in the named
+// directories.
+func yamlFiles(args []string) ([]string, error) {
+	var names []string
+	for _, arg := range args {
+		err := filepath.WalkDir(arg, func(name string, de fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if name == arg && !de.IsDir() {
+				names = append(names, name)
+			} else if !de.IsDir() && formatFromName(name, "") == formatYAML {
+				names = append(names, name)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
+// Diagnostic severities.
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+// A diagnostic is a problem found in a file.
+type diagnostic struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity"`
+	Path     string `json:"path,omitempty"`
+	Message  string `json:"message"`
+}
+
+// validateFile decodes each document in a file, and returns all
+// errors and warnings.
+func validateFile(env *cmdEnv, s *schema, name string, mt protoreflect.MessageType) []diagnostic {
+	r, err := openInput(env, name)
+	if err != nil {
+		return []diagnostic{{File: name, Severity: severityError, Message: err.Error()}}
+	}
+	defer r.Close()
+
+	var diags []diagnostic
+	d := protoyaml.NewDecoder(nil)
+	d.FileName(name)
+	d.MessageTypeResolver(s.types)
+	d.ExtensionTypeResolver(s.types)
+	d.AllErrors(true)
+	d.Warnings(func(w protoyaml.Warning) {
+		diags = append(diags, diagnostic{
+			File:     w.Position.File,
+			Line:     w.Position.Line,
+			Column:   w.Position.Column,
+			Severity: severityWarning,
+			Path:     w.Path,
+			Message:  w.Message,
+		})
+	})
+
+	yd := yaml.NewDecoder(r)
+	for {
+		var doc yaml.Node
+		if err := yd.Decode(&doc); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return append(diags, syntaxDiagnostic(name, err))
+		}
+
+		err := d.DecodeNode(&doc, mt.New())
+		if errs, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, err := range errs.Unwrap() {
+				diags = append(diags, errorDiagnostic(name, &doc, err))
+			}
+		} else if err != nil {
+			diags = append(diags, errorDiagnostic(name, &doc, err))
+		}
+	}
+	return diags
+}
+
+// yamlErrorRE matches the position in a YAML syntax error.
+var yamlErrorRE = regexp.MustCompile(`^yaml: line (\d+): `)
+
+// syntaxDiagnostic returns a diagnostic for a YAML syntax error.
+func syntaxDiagnostic(name string, err error) diagnostic {
+	diag := diagnostic{File: name, Severity: severityError, Message: err.Error()}
+	if m := yamlErrorRE.FindStringSubmatch(diag.Message); m != nil {
+		diag.Line, _ = strconv.Atoi(m[1])
+		diag.Message = diag.Message[len(m[0]):]
+	}
+	return diag
+}
+
+// errorDiagnostic returns a diagnostic for a decoding error. Errors
+// without a position, like missing required fields, are reported at
+// the start of the document.
+func errorDiagnostic(name string, doc *yaml.Node, err error) diagnostic {
+	diag := diagnostic{
+		File:     name,
+		Line:     doc.Line,
+		Column:   doc.Column,
+		Severity: severityError,
+		Message:  strings.TrimPrefix(err.Error(), "protoyaml: "),
+	}
+	var derr *protoyaml.DecodeError
+	if errors.As(err, &derr) {
+		diag.File = derr.Position.File
+		diag.Line = derr.Position.Line
+		diag.Column = derr.Position.Column
+		diag.Path = derr.Path
+		if err == error(derr) {
+			diag.Message = strings.TrimPrefix(derr.Err.Error(), "protoyaml: ")
+		}
+	}
+	return diag
+}
+
+// writeDiagnostics writes diagnostics in the given format.
+func writeDiagnostics(w io.Writer, format string, diags []diagnostic) error {
+	switch format {
+	case "text":
+		for _, diag := range diags {
+			pos := diag.File
+			if diag.Column > 0 {
+				pos = protoyaml.Position{File: diag.File, Line: diag.Line, Column: diag.Column}.String()
+			} else if diag.Line > 0 {
+				pos += ":" + strconv.Itoa(diag.Line)
+			}
+			if _, err := fmt.Fprintf(w, "%s: %s: %s\n", pos, diag.Severity, diag.Message); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case "json":
+		if diags == nil {
+			diags = []diagnostic{}
+		}
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(diags)
+
+	case "github":
+		// See https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions.
+		for _, diag := range diags {
+			props := "file=" + githubEscape(diag.File, true)
+			if diag.Line > 0 {
+				props += ",line=" + strconv.Itoa(diag.Line)
+			}
+			if diag.Column > 0 {
+				props += ",col=" + strconv.Itoa(diag.Column)
+			}
+			if _, err := fmt.Fprintf(w, "::%s %s::%s\n", diag.Severity, props, githubEscape(diag.Message, false)); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// githubEscape escapes a workflow command value, or property value.
+func githubEscape(s string, prop bool) string {
+	s = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+	if prop {
+		s = strings.NewReplacer(":", "%3A", ",", "%2C").Replace(s)
+	}
+	return s
+}
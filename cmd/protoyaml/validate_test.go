@@ -0,0 +1,107 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValidate(t *testing.T) {
+	ds := writeDescriptorSet(t)
+	dir := writeFiles(t, map[string]string{
+		"ok.yaml":          "anint32: 1\n",
+		"conf/bad.yaml":    "anint32: 1\n---\namessage:\n  nosuchfield: 2\n---\nanint32: x\narepeated_int32: [1, y]\n",
+		"conf/old.yaml":    "anold: a\nadeprecated: 1\n",
+		"conf/syntax.yaml": "anint32: [\n",
+		"conf/other.txt":   "not yaml",
+	})
+	rel := func(name string) string { return filepath.Join(dir, name) }
+
+	tsts := []struct {
+		Name   string
+		Format string
+		Status int
+		Want   string
+	}{
+		{
+			Name:   "text",
+			Format: "text",
+			Status: 1,
+			Want: rel("conf/bad.yaml") + ":4:3: error: unknown field: protoyaml.test.Message.nosuchfield\n" +
+				rel("conf/bad.yaml") + ":6:10: error: cannot unmarshal !!str `x` into int32\n" +
+				rel("conf/bad.yaml") + ":7:22: error: cannot unmarshal !!str `y` into int32\n" +
+				rel("conf/old.yaml") + ":1:1: warning: field name anold is deprecated, use arenamed\n" +
+				rel("conf/old.yaml") + ":2:1: warning: field protoyaml.test.Message.adeprecated is deprecated\n" +
+				rel("conf/syntax.yaml") + ":1: error: did not find expected node content\n",
+		},
+		{
+			Name:   "github",
+			Format: "github",
+			Status: 1,
+			Want: "::error file=" + rel("conf/bad.yaml") + ",line=4,col=3::unknown field: protoyaml.test.Message.nosuchfield\n" +
+				"::error file=" + rel("conf/bad.yaml") + ",line=6,col=10::cannot unmarshal !!str `x` into int32\n" +
+				"::error file=" + rel("conf/bad.yaml") + ",line=7,col=22::cannot unmarshal !!str `y` into int32\n" +
+				"::warning file=" + rel("conf/old.yaml") + ",line=1,col=1::field name anold is deprecated, use arenamed\n" +
+				"::warning file=" + rel("conf/old.yaml") + ",line=2,col=1::field protoyaml.test.Message.adeprecated is deprecated\n" +
+				"::error file=" + rel("conf/syntax.yaml") + ",line=1::did not find expected node content\n",
+		},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Name, func(t *testing.T) {
+			status, got, stderr := runCommand(t, "", "validate", "-d", ds, "-type", "protoyaml.test.Message", "-format", tst.Format, rel("ok.yaml"), rel("conf"))
+			if status != tst.Status {
+				t.Errorf("run: got status %d, want %d: %s", status, tst.Status, stderr)
+			}
+			if diff := cmp.Diff(tst.Want, got); diff != "" {
+				t.Errorf("run: +got, -want:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateJSON(t *testing.T) {
+	ds := writeDescriptorSet(t)
+	dir := writeFiles(t, map[string]string{
+		"a.yaml": "amessage:\n  anint32: x\n",
+		"b.yaml": "anint32: 1\n",
+	})
+
+	status, got, _ := runCommand(t, "", "validate", "-d", ds, "-map", "a.yaml=protoyaml.test.Message", "-format", "json", filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml"))
+	if status != 1 {
+		t.Errorf("run: got status %d, want 1", status)
+	}
+	want := `[
+  {
+    "file": "` + filepath.Join(dir, "a.yaml") + `",
+    "line": 2,
+    "column": 12,
+    "severity": "error",
+    "path": "amessage.anint32",
+    "message": "cannot unmarshal !!str ` + "`x`" + ` into int32"
+  },
+  {
+    "file": "` + filepath.Join(dir, "b.yaml") + `",
+    "severity": "error",
+    "message": "no -map matches the file, and there is no -type"
+  }
+]
+`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("run: +got, -want:\n%s", diff)
+	}
+}
+
+func TestValidateOK(t *testing.T) {
+	ds := writeDescriptorSet(t)
+	dir := writeFiles(t, map[string]string{"a.yaml": "anint32: 1\n"})
+
+	status, got, stderr := runCommand(t, "", "validate", "-d", ds, "-map", "*.yaml=protoyaml.test.Message", "-format", "json", dir)
+	if status != 0 {
+		t.Errorf("run: got status %d, want 0: %s", status, stderr)
+	}
+	if got := strings.TrimSpace(got); got != "[]" {
+		t.Errorf("run: got %q, want an empty list", got)
+	}
+}
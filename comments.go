@@ -0,0 +1,53 @@
+package protoyaml
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+)
+
+// SourceComments makes the leading and trailing .proto comments of
+// fields be written as YAML comments, so the output documents itself.
+// Leading comments are placed above the key, and trailing comments at
+// the end of the line. Descriptors without source info, like those of
+// generated code, have no comments.
+func (e *Encoder) SourceComments(enable bool) {
+	e.comments = enable
+}
+
+// addFieldComments adds the comments of fd to the key and value nodes
+// of a field.
+func addFieldComments(kn, vn *yaml.Node, fd protoreflect.FieldDescriptor) {
+	loc := fd.ParentFile().SourceLocations().ByDescriptor(fd)
+	kn.HeadComment = yamlComment(loc.LeadingComments)
+
+	trailing := strings.Join(strings.Fields(loc.TrailingComments), " ")
+	if trailing == "" {
+		return
+	}
+	if len(vn.Content) == 0 {
+		vn.LineComment = "# " + trailing
+	} else {
+		// Non-empty collections are written on the following lines.
+		kn.LineComment = "# " + trailing
+	}
+}
+
+// yamlComment returns a .proto comment as YAML comment lines, or an
+// empty string.
+func yamlComment(s string) string {
+	s = strings.TrimRight(s, " \n")
+	if s == "" {
+		return ""
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line == "" || strings.HasPrefix(line, " ") {
+			lines[i] = "#" + line
+		} else {
+			lines[i] = "# " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,62 @@
+package protoyaml
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestEncoderSourceComments(t *testing.T) {
+	md := compileProto(t, `syntax = "proto3";
+package conf;
+
+message Server {
+  // Where to listen.
+  //
+  // An address and port.
+  string listen = 1;
+  repeated string hosts = 2;  // Virtual hosts.
+  repeated string tags = 3;  // Unused.
+  int32 port = 4;  /* The
+                      port. */
+}
+`, "conf.Server")
+
+	m := dynamicpb.NewMessage(md)
+	if err := Unmarshal([]byte("listen: localhost\nhosts: [a, b]\nport: 80\n"), m); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	e.SourceComments(true)
+	if err := e.Encode(m); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	want := `# Where to listen.
+#
+# An address and port.
+listen: localhost
+hosts: # Virtual hosts.
+    - a
+    - b
+port: 80 # The port.
+`
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("Encode: +got, -want:\n%s", diff)
+	}
+
+	got, err := Marshal(m)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if want := "listen: localhost\nhosts:\n    - a\n    - b\nport: 80\n"; string(got) != want {
+		t.Errorf("Marshal: got %q, want %q", got, want)
+	}
+}
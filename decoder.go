@@ -3,9 +3,13 @@ package protoyaml
 import (
 	"bytes"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"strings"
 
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
 	"gopkg.in/yaml.v3"
@@ -16,20 +20,63 @@ func Unmarshal(bs []byte, m protoreflect.ProtoMessage) error {
 	return NewDecoder(bytes.NewReader(bs)).Decode(m)
 }
 
+// UnmarshalNode populates m from an already parsed YAML node, e.g. a
+// part of a larger YAML document.
+func UnmarshalNode(n *yaml.Node, m protoreflect.ProtoMessage) error {
+	return NewDecoder(nil).DecodeNode(n, m)
+}
+
 // A Decoder can be used to decode one or more YAML documents as
 // Protobuf messages. It is not goroutine-safe, but is
 // goroutine-compatible.
 type Decoder struct {
 	yd *yaml.Decoder
 	r  protoregistry.MessageTypeResolver
+	xr protoregistry.ExtensionTypeResolver
+
+	disc      string
+	discNames map[string]protoreflect.FullName
+
+	allowPartial   bool
+	discardUnknown bool
+	allErrors      bool
+	errs           []error
+
+	warn func(Warning)
+
+	file     string
+	si       *SourceInfo
+	sharedSI bool
+	path     string
+
+	tags    map[string]TagHandler
+	aliases aliasCache
+
+	refFS    fs.FS
+	refFiles map[string]*yaml.Node
+	refStack []refFrame
+	root     *yaml.Node
+
+	lookupEnv func(string) (string, bool)
+	strictEnv bool
+
+	// tagged holds the nodes produced by tag handlers, which are not
+	// expanded. Only used with ExpandEnv.
+	tagged map[*yaml.Node]bool
+
+	patch         bool
+	patchStrategy PatchStrategy
+	patchKeys     map[protoreflect.FullName]protoreflect.Name
 }
 
 // NewDecoder creats a new decoder reading from the given stream of
-// YAML text.
+// YAML text. The reader may be nil if only DecodeNode is used.
 func NewDecoder(r io.Reader) *Decoder {
 	return &Decoder{
-		yd: yaml.NewDecoder(r),
-		r:  protoregistry.GlobalTypes,
+		yd:      yaml.NewDecoder(r),
+		r:       protoregistry.GlobalTypes,
+		xr:      protoregistry.GlobalTypes,
+		aliases: aliasCache{},
 	}
 }
 
@@ -39,37 +86,253 @@ func (d *Decoder) MessageTypeResolver(r protoregistry.MessageTypeResolver) {
 	d.r = r
 }
 
+// ExtensionTypeResolver sets a custom resolver for extension fields,
+// which are written as "[full.name]" keys. The default in NewDecoder
+// is protoregistry.GlobalTypes.
+func (d *Decoder) ExtensionTypeResolver(r protoregistry.ExtensionTypeResolver) {
+	d.xr = r
+}
+
+// Discriminator sets a root key that selects the message type in
+// DecodeNew, e.g. "kind". The value is looked up in names to get the
+// full message name, or used as the full name if names is nil. The
+// message is resolved through the MessageTypeResolver. If the message
+// has a field with the same name, the key is also decoded as a field.
+func (d *Decoder) Discriminator(key string, names map[string]protoreflect.FullName) {
+	d.disc = key
+	d.discNames = names
+}
+
+// AllowPartial disables the check that all required fields are set
+// after decoding a document, like protojson.UnmarshalOptions does.
+func (d *Decoder) AllowPartial(allow bool) {
+	d.allowPartial = allow
+}
+
+// AllErrors makes decoding continue with the next field after a field
+// fails to decode, so all problems in a document are reported, instead
+// of only the first. The returned error then wraps one *DecodeError
+// per failed field, see errors.Join, and the message is left partially
+// decoded. The required fields check is skipped if any field failed.
+func (d *Decoder) AllErrors(enable bool) {
+	d.allErrors = enable
+}
+
+// FileName sets the file name used in the positions of errors and
+// warnings. It is empty by default.
+func (d *Decoder) FileName(file string) {
+	d.file = file
+}
+
+// RecordSourceInfo enables recording of where each field was written
+// in the YAML text, and sets the file name, like FileName does. See
+// SourceInfo. This is also how to get a FieldMask of the fields that
+// were written.
+func (d *Decoder) RecordSourceInfo(file string) {
+	d.file = file
+	d.si = newSourceInfo(nil)
+}
+
+// SourceInfo returns the positions of fields populated by the last
+// decoded document. Returns nil unless RecordSourceInfo has been
+// called.
+func (d *Decoder) SourceInfo() *SourceInfo {
+	return d.si
+}
+
 // Decode decodes the next document as a message. The argument can
 // either be a proto.Message, or a protoreflect.Message. Returns
-// io.EOF if there are no more documents.
+// io.EOF if there are no more documents. Like protojson, it is an
+// error if a required field (proto2 required, or an editions field
+// with LEGACY_REQUIRED presence) is left unset.
 func (d *Decoder) Decode(v interface{}) error {
 	if v == nil {
 		return fmt.Errorf("protoyaml: nil destination message")
 	}
-	n := &yaml.Node{}
-	if err := d.yd.Decode(n); err != nil {
+	n, err := d.nextDocument()
+	if err != nil {
 		return err
 	}
-	if n.Kind == yaml.DocumentNode {
-		n = n.Content[0]
-	}
+	return d.decodeRoot(n, v)
+}
+
+// decodeRoot decodes a document root node into v, which is either a
+// proto.Message, or a protoreflect.Message.
+func (d *Decoder) decodeRoot(n *yaml.Node, v interface{}) error {
 	if n.Kind != yaml.MappingNode {
 		return fmt.Errorf("protoyaml: cannot unmarshal a %v into a %T", n.Kind, v)
 	}
-	switch m := v.(type) {
+	var m protoreflect.Message
+	switch vv := v.(type) {
 	case protoreflect.Message:
-		return d.decodeMessage(m, n, false)
+		m = vv
 	case protoreflect.ProtoMessage:
-		return d.decodeMessage(m.ProtoReflect(), n, false)
+		m = vv.ProtoReflect()
 	default:
 		return fmt.Errorf("protoyaml: cannot unmarshal into a %T", v)
 	}
+	return d.decodeDocument(m, n)
+}
+
+// DecodeNode decodes an already parsed YAML node as a message, instead
+// of reading the next document from the stream. The node can be a
+// document, a mapping, or an alias of a mapping. The argument can
+// either be a proto.Message, or a protoreflect.Message.
+func (d *Decoder) DecodeNode(n *yaml.Node, v interface{}) error {
+	if v == nil {
+		return fmt.Errorf("protoyaml: nil destination message")
+	}
+	n, err := documentRoot(n)
+	if err != nil {
+		return err
+	}
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return d.decodeRoot(n, v)
+}
+
+// DecodeNew decodes the next document as a newly allocated message.
+// The type is selected by the document itself, either through a root
+// "@type" key holding a type URL, like in anypb.Any, or through the
+// key set with Discriminator. Returns io.EOF if there are no more
+// documents.
+func (d *Decoder) DecodeNew() (proto.Message, error) {
+	n, err := d.nextDocument()
+	if err != nil {
+		return nil, err
+	}
+	return d.decodeNewRoot(n)
+}
+
+// decodeNewRoot decodes a document root node as a newly allocated
+// message, as selected by documentType.
+func (d *Decoder) decodeNewRoot(n *yaml.Node) (proto.Message, error) {
+	if n.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("protoyaml: cannot unmarshal a %v into a message", n.Kind)
+	}
+	mt, n, err := d.documentType(n)
+	if err != nil {
+		return nil, err
+	}
+	m := mt.New()
+	if err := d.decodeDocument(m, n); err != nil {
+		return nil, err
+	}
+	return m.Interface(), nil
+}
+
+// nextDocument reads the root node of the next document.
+func (d *Decoder) nextDocument() (*yaml.Node, error) {
+	n := &yaml.Node{}
+	if err := d.yd.Decode(n); err != nil {
+		return nil, err
+	}
+	return documentRoot(n)
+}
+
+// documentRoot returns the root node of a document node, or n if it
+// is not a document. It is an error if the document is empty.
+func documentRoot(n *yaml.Node) (*yaml.Node, error) {
+	if n.Kind != yaml.DocumentNode {
+		return n, nil
+	}
+	if len(n.Content) == 0 {
+		return nil, fmt.Errorf("protoyaml: empty document")
+	}
+	return n.Content[0], nil
+}
+
+// decodeDocument decodes the root node of a document as a message.
+func (d *Decoder) decodeDocument(m protoreflect.Message, n *yaml.Node) error {
+	if d.si != nil && !d.sharedSI {
+		d.si = newSourceInfo(m.Descriptor())
+	}
+	d.path = ""
+	d.root = n
+	d.errs = nil
+	d.tagged = nil
+	if err := d.decodeMessage(m, n, false); err != nil {
+		return err
+	}
+	if len(d.errs) == 1 {
+		return d.errs[0]
+	} else if len(d.errs) > 0 {
+		return errors.Join(d.errs...)
+	}
+	if d.allowPartial {
+		return nil
+	}
+	return proto.CheckInitialized(m.Interface())
+}
+
+// documentType finds the message type of a document root mapping. The
+// returned node has the type selection key removed, unless it is
+// also a field of the message.
+func (d *Decoder) documentType(v *yaml.Node) (protoreflect.MessageType, *yaml.Node, error) {
+	for i := 0; i+1 < len(v.Content); i += 2 {
+		key, n := v.Content[i].Value, v.Content[i+1]
+		switch {
+		case key == "@type":
+			mt, err := d.r.FindMessageByURL(n.Value)
+			if err != nil {
+				return nil, nil, err
+			}
+			return mt, withoutKey(v, i), nil
+
+		case d.disc != "" && key == d.disc:
+			name := protoreflect.FullName(n.Value)
+			if d.discNames != nil {
+				var ok bool
+				name, ok = d.discNames[n.Value]
+				if !ok {
+					return nil, nil, fmt.Errorf("protoyaml: unknown %s: %s", key, n.Value)
+				}
+			}
+			mt, err := d.r.FindMessageByName(name)
+			if err != nil {
+				return nil, nil, err
+			}
+			if mt.Descriptor().Fields().ByName(protoreflect.Name(key)) != nil {
+				return mt, v, nil
+			}
+			return mt, withoutKey(v, i), nil
+		}
+	}
+
+	if d.disc != "" {
+		return nil, nil, fmt.Errorf("protoyaml: no @type or %s key in document", d.disc)
+	}
+	return nil, nil, fmt.Errorf("protoyaml: no @type key in document")
+}
+
+// withoutKey returns a shallow copy of the mapping v, with the key at
+// index i, and its value, removed.
+func withoutKey(v *yaml.Node, i int) *yaml.Node {
+	n := *v
+	n.Content = append(append([]*yaml.Node{}, v.Content[:i]...), v.Content[i+2:]...)
+	return &n
 }
 
 // decodeMessage decodes the given node as a Protobuf message.
 func (d *Decoder) decodeMessage(out protoreflect.Message, v *yaml.Node, preserve bool) error {
-	if v.Kind == yaml.AliasNode {
-		v = v.Alias
+	return d.decodeMessageFields(out, v, preserve, map[protoreflect.FieldNumber]bool{})
+}
+
+// decodeMessageFields decodes the given node as a Protobuf message,
+// recording the fields it sets in seen. If preserve is true, fields
+// already set are left alone. Fields with implicit presence cannot be
+// told apart from unset fields when they hold the zero value, so seen
+// is what makes merge keys respect an explicit `field: 0`.
+func (d *Decoder) decodeMessageFields(out protoreflect.Message, v *yaml.Node, preserve bool, seen map[protoreflect.FieldNumber]bool) error {
+	v, err := d.resolveNode(v)
+	if err != nil {
+		return err
+	}
+	if d.isRef(v) {
+		return d.decodeRef(v, func(n *yaml.Node) error {
+			return d.decodeMessageFields(out, n, preserve, seen)
+		})
 	}
 
 	if ok, err := d.decodeKnownType(out, v); err != nil {
@@ -82,53 +345,135 @@ func (d *Decoder) decodeMessage(out protoreflect.Message, v *yaml.Node, preserve
 		return fmt.Errorf("protoyaml: attempting to decode a %v into a message: %s", v.Kind, out.Descriptor().FullName())
 	}
 
-	var key string
-	for _, n := range v.Content {
-		if key == "" {
-			key = n.Value
-			continue
-		}
+	for i := 0; i+1 < len(v.Content); i += 2 {
+		kn, n := v.Content[i], v.Content[i+1]
+		key := kn.Value
 
-		if key == "<<" {
+		if isMergeKey(kn) {
 			// See https://yaml.org/type/merge.html.
 			if n.Kind == yaml.SequenceNode {
 				for _, n := range n.Content {
-					if err := d.decodeMessage(out, n, true); err != nil {
+					if err := d.decodeMessageFields(out, n, true, seen); err != nil {
 						return err
 					}
 				}
 			} else {
-				if err := d.decodeMessage(out, n, true); err != nil {
+				if err := d.decodeMessageFields(out, n, true, seen); err != nil {
 					return err
 				}
 			}
-		} else {
-			fd := out.Descriptor().Fields().ByName(protoreflect.Name(key))
-			if fd == nil {
-				return fmt.Errorf("protoyaml: unknown field: %s.%s", out.Descriptor().FullName(), key)
+			continue
+		}
+
+		fd, err := d.findField(out.Descriptor(), key)
+		if err != nil {
+			if err := d.fieldError(d.positionError(kn, err)); err != nil {
+				return err
+			}
+			continue
+		} else if fd == nil {
+			d.warnf(kn, WarnUnknownField, keyPath(d.path, key), "unknown field %s.%s discarded", out.Descriptor().FullName(), key)
+			continue
+		}
+		parent := d.path
+		d.path = fieldPath(parent, fd)
+		if isDeprecatedField(fd) {
+			d.warnf(kn, WarnDeprecatedField, d.path, "field %s is deprecated", fd.FullName())
+		}
+		if !fd.IsExtension() && key != string(fd.Name()) {
+			d.warnf(kn, WarnFieldAlias, d.path, "field name %s is deprecated, use %s", key, fd.Name())
+		}
+		if seen[fd.Number()] {
+			if preserve {
+				d.path = parent
+				continue
+			}
+
+			out.Clear(fd)
+			d.si.forget(d.path)
+		} else if out.Has(fd) {
+			// The field was set before this document. In patch mode,
+			// merge keys override it like any other key.
+			if preserve && !d.patch {
+				d.path = parent
+				continue
 			}
-			if out.Has(fd) {
-				if preserve {
-					key = ""
-					continue
-				}
 
+			if !d.patchesInPlace(fd) {
 				out.Clear(fd)
+				d.si.forget(d.path)
 			}
-			if err := d.decodeField(out, fd, n); err != nil {
+		}
+		seen[fd.Number()] = true
+		if d.patch && isDeletion(n) {
+			out.Clear(fd)
+			d.si.forget(d.path)
+			d.path = parent
+			continue
+		}
+		d.si.record(d.path, d.nodePosition(kn))
+		if err := d.decodeField(out, fd, n); err != nil {
+			if err := d.fieldError(d.positionError(n, err)); err != nil {
 				return err
 			}
 		}
-		key = ""
+		d.path = parent
+	}
+	return nil
+}
+
+// fieldError returns an error decoding a field. With AllErrors, it is
+// instead collected, and nil is returned, so decoding continues.
+func (d *Decoder) fieldError(err error) error {
+	if !d.allErrors {
+		return err
 	}
+	d.errs = append(d.errs, err)
 	return nil
 }
 
+// findField returns the descriptor of a regular or extension field
+// named by a mapping key. Former names set with the
+// (protoyaml.field).aliases option are also accepted. Returns nil if
+// there is no such field, and unknown fields are discarded.
+func (d *Decoder) findField(md protoreflect.MessageDescriptor, key string) (protoreflect.FieldDescriptor, error) {
+	if strings.HasPrefix(key, "[") && strings.HasSuffix(key, "]") {
+		xt, err := d.xr.FindExtensionByName(protoreflect.FullName(key[1 : len(key)-1]))
+		if err == protoregistry.NotFound && d.discardUnknown {
+			return nil, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("protoyaml: unknown extension field: %s%s: %w", md.FullName(), key, err)
+		}
+		xd := xt.TypeDescriptor()
+		if xd.ContainingMessage().FullName() != md.FullName() {
+			return nil, fmt.Errorf("protoyaml: extension field %s does not extend %s", xd.FullName(), md.FullName())
+		}
+		return xd, nil
+	}
+
+	fd := md.Fields().ByName(protoreflect.Name(key))
+	if fd == nil {
+		fd = d.aliases.find(md, key)
+	}
+	if fd == nil && d.discardUnknown {
+		return nil, nil
+	} else if fd == nil {
+		return nil, fmt.Errorf("protoyaml: unknown field: %s.%s", md.FullName(), key)
+	}
+	return fd, nil
+}
+
 // decodeField decodes some value guided by a field descriptor. This
 // is the main workhorse of the decoder.
 func (d *Decoder) decodeField(out protoreflect.Message, fd protoreflect.FieldDescriptor, v *yaml.Node) error {
-	if v.Kind == yaml.AliasNode {
-		v = v.Alias
+	v, err := d.resolveNode(v)
+	if err != nil {
+		return err
+	}
+	if d.isRef(v) {
+		return d.decodeRef(v, func(n *yaml.Node) error {
+			return d.decodeField(out, fd, n)
+		})
 	}
 
 	if fd.IsMap() {
@@ -138,23 +483,38 @@ func (d *Decoder) decodeField(out protoreflect.Message, fd protoreflect.FieldDes
 
 		mp := out.Mutable(fd).Map()
 		var key protoreflect.MapKey
+		var kn *yaml.Node
+		var merge bool
 		for _, n := range v.Content {
 			if key.IsValid() {
-				if fd.MapKey().Kind() == protoreflect.StringKind && key.String() == "<<" {
+				if merge {
 					if err := d.decodeField(out, fd, n); err != nil {
 						return err
 					}
-				} else if fd.MapValue().Kind() == protoreflect.MessageKind {
-					pv := mp.Mutable(key)
-					if err := d.decodeMessage(pv.Message(), n, false); err != nil {
-						return err
-					}
+				} else if d.patch && isDeletion(n) {
+					mp.Clear(key)
+					d.si.forget(mapPath(d.path, key))
 				} else {
-					pv, err := d.decodeValue(fd.MapValue(), n)
-					if err != nil {
-						return err
+					parent := d.path
+					d.path = mapPath(parent, key)
+					if mp.Has(key) {
+						// Only overwritten entries have old positions.
+						d.si.forget(d.path)
+					}
+					d.si.record(d.path, d.nodePosition(kn))
+					if isMessageKind(fd.MapValue()) {
+						pv := mp.Mutable(key)
+						if err := d.decodeMessage(pv.Message(), n, false); err != nil {
+							return err
+						}
+					} else {
+						pv, err := d.decodeValue(fd.MapValue(), n)
+						if err != nil {
+							return err
+						}
+						mp.Set(key, pv)
 					}
-					mp.Set(key, pv)
+					d.path = parent
 				}
 				key = protoreflect.MapKey{}
 			} else {
@@ -169,6 +529,8 @@ func (d *Decoder) decodeField(out protoreflect.Message, fd protoreflect.FieldDes
 					return fmt.Errorf("protoyaml: attempting to use %T as a map key in %q", pv.Interface(), fd.FullName())
 				}
 				key = pv.MapKey()
+				kn = n
+				merge = isMergeKey(n)
 			}
 		}
 		return nil
@@ -180,8 +542,17 @@ func (d *Decoder) decodeField(out protoreflect.Message, fd protoreflect.FieldDes
 		}
 
 		l := out.Mutable(fd).List()
+		if kfd, err := d.patchKeyField(fd); err != nil {
+			return err
+		} else if kfd != nil {
+			return d.decodeKeyedList(l, fd, kfd, v)
+		}
+
+		parent := d.path
 		for _, n := range v.Content {
-			if fd.Kind() == protoreflect.MessageKind {
+			d.path = listPath(parent, l.Len())
+			d.si.record(d.path, d.nodePosition(n))
+			if isMessageKind(fd) {
 				pv := l.AppendMutable()
 				if err := d.decodeMessage(pv.Message(), n, false); err != nil {
 					return err
@@ -195,10 +566,11 @@ func (d *Decoder) decodeField(out protoreflect.Message, fd protoreflect.FieldDes
 			}
 			l.Append(pv)
 		}
+		d.path = parent
 		return nil
 	}
 
-	if fd.Kind() == protoreflect.MessageKind {
+	if isMessageKind(fd) {
 		return d.decodeMessage(out.Mutable(fd).Message(), v, false)
 	}
 
@@ -221,6 +593,33 @@ func (d *Decoder) decodeField(out protoreflect.Message, fd protoreflect.FieldDes
 // decodeValue decodes a non-compound value, interpreted based on the
 // kind of field it is.
 func (d *Decoder) decodeValue(fd protoreflect.FieldDescriptor, v *yaml.Node) (protoreflect.Value, error) {
+	pv, err := d.decodeScalar(fd, v)
+	if err != nil {
+		return protoreflect.Value{}, d.positionError(v, err)
+	}
+	return pv, nil
+}
+
+// decodeScalar implements decodeValue.
+func (d *Decoder) decodeScalar(fd protoreflect.FieldDescriptor, v *yaml.Node) (protoreflect.Value, error) {
+	v, err := d.resolveNode(v)
+	if err != nil {
+		return protoreflect.Value{}, err
+	}
+	if d.isRef(v) {
+		var pv protoreflect.Value
+		err := d.decodeRef(v, func(n *yaml.Node) error {
+			var err error
+			pv, err = d.decodeValue(fd, n)
+			return err
+		})
+		return pv, err
+	}
+	v, err = d.expandScalar(v)
+	if err != nil {
+		return protoreflect.Value{}, err
+	}
+
 	switch fd.Kind() {
 	case protoreflect.BoolKind:
 		var vv bool
@@ -272,6 +671,16 @@ func (d *Decoder) decodeValue(fd protoreflect.FieldDescriptor, v *yaml.Node) (pr
 		return protoreflect.ValueOfFloat64(vv), nil
 
 	case protoreflect.StringKind:
+		// FileTag returns !!binary, so file contents can be stored in
+		// both string and bytes fields. Like yaml.v3 does for Go
+		// strings, the string is the decoded data.
+		if v.ShortTag() == "!!binary" {
+			bs, err := base64.StdEncoding.DecodeString(v.Value)
+			if err != nil {
+				return protoreflect.Value{}, err
+			}
+			return protoreflect.ValueOfString(string(bs)), nil
+		}
 		return protoreflect.ValueOfString(v.Value), nil
 
 	case protoreflect.BytesKind:
@@ -283,17 +692,92 @@ func (d *Decoder) decodeValue(fd protoreflect.FieldDescriptor, v *yaml.Node) (pr
 
 	case protoreflect.EnumKind:
 		evd := fd.Enum().Values().ByName(protoreflect.Name(v.Value))
-		if evd != nil {
-			return protoreflect.ValueOfEnum(evd.Number()), nil
+		if evd == nil {
+			var vv int32
+			if err := v.Decode(&vv); err != nil {
+				return protoreflect.Value{}, err
+			}
+			evd = fd.Enum().Values().ByNumber(protoreflect.EnumNumber(vv))
+			if evd == nil {
+				if fd.Enum().IsClosed() {
+					return protoreflect.Value{}, fmt.Errorf("protoyaml: invalid value for closed enum %s: %d", fd.Enum().FullName(), vv)
+				}
+				return protoreflect.ValueOfEnum(protoreflect.EnumNumber(vv)), nil
+			}
 		}
-
-		var vv int32
-		if err := v.Decode(&vv); err != nil {
-			return protoreflect.Value{}, err
+		if isDeprecatedEnumValue(evd) {
+			d.warnf(v, WarnDeprecatedEnumValue, d.path, "enum value %s is deprecated", evd.FullName())
 		}
-		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(vv)), nil
+		return protoreflect.ValueOfEnum(evd.Number()), nil
 
 	default:
 		return protoreflect.Value{}, fmt.Errorf("protoyaml: cannot unmarshal a %v into a %v", v.Kind, fd.Kind())
 	}
 }
+
+// nodePosition returns the position of a node in the file currently
+// being decoded, which is a referenced file while decoding a $ref.
+func (d *Decoder) nodePosition(n *yaml.Node) Position {
+	file := d.file
+	if len(d.refStack) > 0 {
+		file = d.refStack[len(d.refStack)-1].file
+	}
+	return Position{File: file, Line: n.Line, Column: n.Column}
+}
+
+// A DecodeError is a failure to decode a value at a known position.
+// Use errors.As to find it, since errors from $ref targets are
+// wrapped to also show the referencing position.
+type DecodeError struct {
+	Position Position
+
+	// Path is the field path of the value, or empty for the root.
+	Path string
+
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("protoyaml: %v: %s", e.Position, strings.TrimPrefix(e.Err.Error(), "protoyaml: "))
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// nodeErrorf returns a *DecodeError at the position of n.
+func (d *Decoder) nodeErrorf(n *yaml.Node, format string, args ...interface{}) error {
+	return &DecodeError{Position: d.nodePosition(n), Path: d.path, Err: fmt.Errorf(format, args...)}
+}
+
+// positionError returns err as a *DecodeError at the position of n,
+// unless it already has a position.
+func (d *Decoder) positionError(n *yaml.Node, err error) error {
+	var derr *DecodeError
+	if errors.As(err, &derr) {
+		return err
+	}
+	var terr *yaml.TypeError
+	if errors.As(err, &terr) && len(terr.Errors) == 1 {
+		// The position is in the DecodeError.
+		msg := terr.Errors[0]
+		if _, rest, ok := strings.Cut(msg, ": "); ok && strings.HasPrefix(msg, "line ") {
+			msg = rest
+		}
+		err = errors.New(msg)
+	}
+	return &DecodeError{Position: d.nodePosition(n), Path: d.path, Err: err}
+}
+
+// isMergeKey returns true if the node is a plain "<<" key. A quoted
+// "<<" is an ordinary string, as in yaml.v3.
+func isMergeKey(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.Value == "<<" && n.ShortTag() == "!!merge"
+}
+
+// isMessageKind returns true if the field holds a message, regardless
+// of whether it is length-prefixed or delimited (a proto2 group, or
+// the editions message_encoding = DELIMITED feature).
+func isMessageKind(fd protoreflect.FieldDescriptor) bool {
+	return fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind
+}
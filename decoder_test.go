@@ -1,44 +1,55 @@
 package protoyaml
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
 	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
 	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
 	"google.golang.org/protobuf/types/known/durationpb"
 	"gopkg.in/yaml.v3"
 
 	"github.com/tommie/protoyaml-go/internal/testproto"
 )
 
-func ExampleUnmarshal() (*testproto.Message, error) {
+func ExampleUnmarshal() {
 	var got testproto.Message
 	if err := Unmarshal([]byte(`astring: hello`), &got); err != nil {
-		return nil, err
+		panic(err)
 	}
-	return &got, nil
+	fmt.Println(got.Astring)
+	// Output: hello
 }
 
-func ExampleDecoder_Decode(yamlString string) ([]*testproto.Message, error) {
-	d := NewDecoder(strings.NewReader(yamlString))
+func ExampleDecoder_Decode() {
+	d := NewDecoder(strings.NewReader(`astring: hello
+---
+astring: world`))
 
-	var outs []*testproto.Message
 	for {
 		var m testproto.Message
 		err := d.Decode(&m)
 		if err == io.EOF {
 			break
 		} else if err != nil {
-			return nil, err
+			panic(err)
 		}
-		outs = append(outs, &m)
+		fmt.Println(m.Astring)
 	}
-
-	return outs, nil
+	// Output:
+	// hello
+	// world
 }
 
 func TestUnmarshal(t *testing.T) {
@@ -53,6 +64,39 @@ func TestUnmarshal(t *testing.T) {
 	}
 }
 
+func TestUnmarshalNode(t *testing.T) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(`config:
+  name: other
+  proto: &anchor
+    astring: hello
+alias: *anchor`), &doc); err != nil {
+		t.Fatalf("yaml.Unmarshal failed: %v", err)
+	}
+
+	root := doc.Content[0]
+	for name, n := range map[string]*yaml.Node{"mapping": root.Content[1].Content[3], "alias": root.Content[3], "document": &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root.Content[1].Content[3]}}} {
+		t.Run(name, func(t *testing.T) {
+			var got testproto.Message
+			if err := UnmarshalNode(n, &got); err != nil {
+				t.Fatalf("UnmarshalNode failed: %v", err)
+			}
+
+			want := &testproto.Message{Astring: "hello"}
+			if diff := cmp.Diff(want, &got, protocmp.Transform()); diff != "" {
+				t.Errorf("UnmarshalNode: +got, -want:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecoderEmptyDocument(t *testing.T) {
+	var got testproto.Message
+	if err := UnmarshalNode(&yaml.Node{Kind: yaml.DocumentNode}, &got); err == nil || err.Error() != "protoyaml: empty document" {
+		t.Errorf("UnmarshalNode: got %v, want an empty document error", err)
+	}
+}
+
 func TestDecoderDecode(t *testing.T) {
 	t.Run("Message", func(t *testing.T) {
 		var got testproto.Message
@@ -92,11 +136,11 @@ anint32: 42`))
 		tsts := []struct {
 			Name string
 			YAML string
-			Want testproto.Message
+			Want *testproto.Message
 		}{
-			{"int32", `arepeated_message: [ {anint32: &anchor 42}, {anint32: *anchor} ]`, testproto.Message{Anint32: 42}},
-			{"message", `arepeated_message: [ &anchor {anint32: 42}, *anchor ]`, testproto.Message{Anint32: 42}},
-			{"repeated", `arepeated_message: [ {arepeated_bool: &anchor [true, false] }, {arepeated_bool: *anchor } ]`, testproto.Message{ArepeatedBool: []bool{true, false}}},
+			{"int32", `arepeated_message: [ {anint32: &anchor 42}, {anint32: *anchor} ]`, &testproto.Message{Anint32: 42}},
+			{"message", `arepeated_message: [ &anchor {anint32: 42}, *anchor ]`, &testproto.Message{Anint32: 42}},
+			{"repeated", `arepeated_message: [ {arepeated_bool: &anchor [true, false] }, {arepeated_bool: *anchor } ]`, &testproto.Message{ArepeatedBool: []bool{true, false}}},
 		}
 		for _, tst := range tsts {
 			t.Run(tst.Name, func(t *testing.T) {
@@ -105,7 +149,7 @@ anint32: 42`))
 					t.Fatalf("Decode failed: %v", err)
 				}
 
-				if diff := cmp.Diff(&tst.Want, got.ArepeatedMessage[1], protocmp.Transform()); diff != "" {
+				if diff := cmp.Diff(tst.Want, got.ArepeatedMessage[1], protocmp.Transform()); diff != "" {
 					t.Errorf("Decode: +got, -want:\n%s", diff)
 				}
 			})
@@ -113,6 +157,71 @@ anint32: 42`))
 	})
 }
 
+func TestDecoderDecodeNew(t *testing.T) {
+	t.Run("type", func(t *testing.T) {
+		d := NewDecoder(strings.NewReader(`"@type": type.googleapis.com/protoyaml.test.Message
+astring: hello
+---
+"@type": type.googleapis.com/protoyaml.test.Known
+aduration: 42s`))
+
+		var got []proto.Message
+		for {
+			m, err := d.DecodeNew()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("DecodeNew failed: %v", err)
+			}
+			got = append(got, m)
+		}
+
+		want := []proto.Message{&testproto.Message{Astring: "hello"}, &testproto.Known{Aduration: durationpb.New(42 * time.Second)}}
+		if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+			t.Errorf("DecodeNew: +got, -want:\n%s", diff)
+		}
+	})
+
+	t.Run("discriminator", func(t *testing.T) {
+		d := NewDecoder(strings.NewReader(`{kind: Message, astring: hello}`))
+		d.Discriminator("kind", map[string]protoreflect.FullName{"Message": "protoyaml.test.Message"})
+
+		got, err := d.DecodeNew()
+		if err != nil {
+			t.Fatalf("DecodeNew failed: %v", err)
+		}
+
+		want := &testproto.Message{Astring: "hello"}
+		if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+			t.Errorf("DecodeNew: +got, -want:\n%s", diff)
+		}
+	})
+
+	t.Run("discriminatorField", func(t *testing.T) {
+		d := NewDecoder(strings.NewReader(`{astring: protoyaml.test.Message, anint32: 42}`))
+		d.Discriminator("astring", nil)
+
+		got, err := d.DecodeNew()
+		if err != nil {
+			t.Fatalf("DecodeNew failed: %v", err)
+		}
+
+		want := &testproto.Message{Astring: "protoyaml.test.Message", Anint32: 42}
+		if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+			t.Errorf("DecodeNew: +got, -want:\n%s", diff)
+		}
+	})
+
+	t.Run("missingType", func(t *testing.T) {
+		d := NewDecoder(strings.NewReader(`{kind: Other, astring: hello}`))
+		d.Discriminator("kind", map[string]protoreflect.FullName{"Message": "protoyaml.test.Message"})
+
+		if _, err := d.DecodeNew(); err == nil {
+			t.Errorf("DecodeNew: got nil error, want unknown kind")
+		}
+	})
+}
+
 func TestDecoderDecodeMessage(t *testing.T) {
 	t.Run("simple", func(t *testing.T) {
 		d, n, err := parseYAML(`anint32: 42
@@ -205,18 +314,18 @@ func TestDecoderDecodeField(t *testing.T) {
 		Name string
 		YAML string
 		FD   protoreflect.FieldDescriptor
-		Want testproto.Message
+		Want *testproto.Message
 	}{
-		{"scalar", `42`, fds.ByName("anint32"), testproto.Message{Anint32: 42}},
+		{"scalar", `42`, fds.ByName("anint32"), &testproto.Message{Anint32: 42}},
 
-		{"scalarSequence", `[42, 43]`, fds.ByName("arepeated_int32"), testproto.Message{ArepeatedInt32: []int32{42, 43}}},
-		{"messageSequence", `[{anint64: 42}, {anint64: 43}]`, fds.ByName("arepeated_message"), testproto.Message{ArepeatedMessage: []*testproto.Message{{Anint64: 42}, {Anint64: 43}}}},
-		{"scalarSequence", `[42, 43]`, fds.ByName("arepeated_int32"), testproto.Message{ArepeatedInt32: []int32{42, 43}}},
+		{"scalarSequence", `[42, 43]`, fds.ByName("arepeated_int32"), &testproto.Message{ArepeatedInt32: []int32{42, 43}}},
+		{"messageSequence", `[{anint64: 42}, {anint64: 43}]`, fds.ByName("arepeated_message"), &testproto.Message{ArepeatedMessage: []*testproto.Message{{Anint64: 42}, {Anint64: 43}}}},
+		{"scalarSequence", `[42, 43]`, fds.ByName("arepeated_int32"), &testproto.Message{ArepeatedInt32: []int32{42, 43}}},
 
-		{"messageMapping", `{anint32: 42}`, fds.ByName("amessage"), testproto.Message{Amessage: &testproto.Message{Anint32: 42}}},
-		{"scalarMapMapping", `{anykey: 42}`, fds.ByName("astring_int32_map"), testproto.Message{AstringInt32Map: map[string]int32{"anykey": 42}}},
-		{"scalarMapMappingMerge", `{<< : {anykey: 42}, another: 43}`, fds.ByName("astring_int32_map"), testproto.Message{AstringInt32Map: map[string]int32{"anykey": 42, "another": 43}}},
-		{"messageMapMapping", `{anykey: {anint32: 42}}`, fds.ByName("astring_message_map"), testproto.Message{AstringMessageMap: map[string]*testproto.Message{"anykey": {Anint32: 42}}}},
+		{"messageMapping", `{anint32: 42}`, fds.ByName("amessage"), &testproto.Message{Amessage: &testproto.Message{Anint32: 42}}},
+		{"scalarMapMapping", `{anykey: 42}`, fds.ByName("astring_int32_map"), &testproto.Message{AstringInt32Map: map[string]int32{"anykey": 42}}},
+		{"scalarMapMappingMerge", `{<< : {anykey: 42}, another: 43}`, fds.ByName("astring_int32_map"), &testproto.Message{AstringInt32Map: map[string]int32{"anykey": 42, "another": 43}}},
+		{"messageMapMapping", `{anykey: {anint32: 42}}`, fds.ByName("astring_message_map"), &testproto.Message{AstringMessageMap: map[string]*testproto.Message{"anykey": {Anint32: 42}}}},
 	}
 	for _, tst := range tsts {
 		t.Run(tst.Name, func(t *testing.T) {
@@ -229,7 +338,7 @@ func TestDecoderDecodeField(t *testing.T) {
 				t.Fatalf("decodeField failed: %v", err)
 			}
 
-			if diff := cmp.Diff(&tst.Want, &got, protocmp.Transform()); diff != "" {
+			if diff := cmp.Diff(tst.Want, &got, protocmp.Transform()); diff != "" {
 				t.Errorf("decodeField: +got, -want:\n%s", diff)
 			}
 		})
@@ -288,6 +397,220 @@ func TestDecoderDecodeValue(t *testing.T) {
 	}
 }
 
+func TestDecoderDecodeError(t *testing.T) {
+	tsts := []struct {
+		Name string
+		YAML string
+		Want DecodeError
+	}{
+		{"unknownField", "amessage:\n  nosuchfield: 1\n", DecodeError{Position{"a.yaml", 2, 3}, "amessage", nil}},
+		{"badScalar", "arepeated_int32: [1, x]\n", DecodeError{Position{"a.yaml", 1, 22}, "arepeated_int32[1]", nil}},
+		{"badKind", "amessage: [1]\n", DecodeError{Position{"a.yaml", 1, 11}, "amessage", nil}},
+		{"quotedMergeKey", "amessage:\n  '<<': {anint32: 1}\n", DecodeError{Position{"a.yaml", 2, 3}, "amessage", nil}},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Name, func(t *testing.T) {
+			d := NewDecoder(strings.NewReader(tst.YAML))
+			d.RecordSourceInfo("a.yaml")
+
+			var got testproto.Message
+			err := d.Decode(&got)
+			var derr *DecodeError
+			if !errors.As(err, &derr) {
+				t.Fatalf("Decode: got %v, want a DecodeError", err)
+			}
+
+			if diff := cmp.Diff(tst.Want, *derr, cmpopts.IgnoreFields(DecodeError{}, "Err")); diff != "" {
+				t.Errorf("Decode: +got, -want:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecoderAllErrors(t *testing.T) {
+	d := NewDecoder(strings.NewReader("anint32: x\namessage: {nosuch: 1, astring: a}\nabool: true\n"))
+	d.FileName("a.yaml")
+	d.AllErrors(true)
+
+	var got testproto.Message
+	err := d.Decode(&got)
+	want := "protoyaml: a.yaml:1:10: cannot unmarshal !!str `x` into int32\n" +
+		"protoyaml: a.yaml:2:12: unknown field: protoyaml.test.Message.nosuch"
+	if err == nil || err.Error() != want {
+		t.Errorf("Decode: got %v, want %q", err, want)
+	}
+	var derr *DecodeError
+	if !errors.As(err, &derr) || derr.Path != "anint32" {
+		t.Errorf("Decode: got %v, want a DecodeError for anint32", err)
+	}
+
+	wantM := &testproto.Message{Amessage: &testproto.Message{Astring: "a"}, Abool: true}
+	if diff := cmp.Diff(wantM, &got, protocmp.Transform()); diff != "" {
+		t.Errorf("Decode: +got, -want:\n%s", diff)
+	}
+}
+
+func TestDecoderFileName(t *testing.T) {
+	d := NewDecoder(strings.NewReader("anint32: x\n"))
+	d.FileName("a.yaml")
+
+	var got testproto.Message
+	err := d.Decode(&got)
+	if want := "protoyaml: a.yaml:1:10: cannot unmarshal !!str `x` into int32"; err == nil || err.Error() != want {
+		t.Errorf("Decode: got %v, want %q", err, want)
+	}
+	if si := d.SourceInfo(); si != nil {
+		t.Errorf("SourceInfo: got %v, want nil", si)
+	}
+}
+
+func TestDecoderDecodeEditions(t *testing.T) {
+	fd := editionsFile(t)
+	md := fd.Messages().ByName("Message")
+	fds := md.Fields()
+
+	t.Run("implicitPresenceMerge", func(t *testing.T) {
+		got := dynamicpb.NewMessage(md)
+		if err := Unmarshal([]byte(`{implicit: 0, << : {implicit: 42}}`), got); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+
+		if got := got.Get(fds.ByName("implicit")).Int(); got != 0 {
+			t.Errorf("Unmarshal implicit: got %v, want 0", got)
+		}
+	})
+
+	t.Run("explicitPresence", func(t *testing.T) {
+		got := dynamicpb.NewMessage(md)
+		if err := Unmarshal([]byte(`{explicit: 0, << : {explicit: 42}}`), got); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+
+		if !got.Has(fds.ByName("explicit")) {
+			t.Errorf("Unmarshal explicit: not set")
+		}
+		if got := got.Get(fds.ByName("explicit")).Int(); got != 0 {
+			t.Errorf("Unmarshal explicit: got %v, want 0", got)
+		}
+	})
+
+	t.Run("closedEnum", func(t *testing.T) {
+		got := dynamicpb.NewMessage(md)
+		if err := Unmarshal([]byte(`{closed: 1, open: 2}`), got); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+
+		if got := got.Get(fds.ByName("closed")).Enum(); got != 1 {
+			t.Errorf("Unmarshal closed: got %v, want 1", got)
+		}
+		if got := got.Get(fds.ByName("open")).Enum(); got != 2 {
+			t.Errorf("Unmarshal open: got %v, want 2", got)
+		}
+	})
+
+	t.Run("closedEnumUnknown", func(t *testing.T) {
+		got := dynamicpb.NewMessage(md)
+		if err := Unmarshal([]byte(`closed: 2`), got); err == nil {
+			t.Errorf("Unmarshal: got nil error, want unknown enum value")
+		}
+	})
+
+	t.Run("delimited", func(t *testing.T) {
+		got := dynamicpb.NewMessage(md)
+		if err := Unmarshal([]byte(`{delimited: {implicit: 42}, arepeated_delimited: [{implicit: 43}]}`), got); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+
+		if got := got.Get(fds.ByName("delimited")).Message().Get(fds.ByName("implicit")).Int(); got != 42 {
+			t.Errorf("Unmarshal delimited: got %v, want 42", got)
+		}
+		if got := got.Get(fds.ByName("arepeated_delimited")).List().Get(0).Message().Get(fds.ByName("implicit")).Int(); got != 43 {
+			t.Errorf("Unmarshal arepeated_delimited: got %v, want 43", got)
+		}
+	})
+
+	t.Run("legacyRequired", func(t *testing.T) {
+		rmd := fd.Messages().ByName("Required")
+		if err := Unmarshal([]byte(`optional: 42`), dynamicpb.NewMessage(rmd)); err == nil {
+			t.Errorf("Unmarshal: got nil error, want missing required field")
+		}
+		if err := Unmarshal([]byte(`required: 42`), dynamicpb.NewMessage(rmd)); err != nil {
+			t.Errorf("Unmarshal failed: %v", err)
+		}
+	})
+}
+
+// editionsFile builds an edition 2023 file descriptor exercising the
+// features the decoder honors.
+func editionsFile(t *testing.T) protoreflect.FileDescriptor {
+	t.Helper()
+
+	field := func(name string, num int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string, features *descriptorpb.FeatureSet) *descriptorpb.FieldDescriptorProto {
+		fdp := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(num),
+			Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:   typ.Enum(),
+		}
+		if typeName != "" {
+			fdp.TypeName = proto.String(typeName)
+		}
+		if features != nil {
+			fdp.Options = &descriptorpb.FieldOptions{Features: features}
+		}
+		return fdp
+	}
+	enum := func(name string, typ descriptorpb.FeatureSet_EnumType) *descriptorpb.EnumDescriptorProto {
+		return &descriptorpb.EnumDescriptorProto{
+			Name: proto.String(name),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String(name + "_ZERO"), Number: proto.Int32(0)},
+				{Name: proto.String(name + "_ONE"), Number: proto.Int32(1)},
+			},
+			Options: &descriptorpb.EnumOptions{Features: &descriptorpb.FeatureSet{EnumType: typ.Enum()}},
+		}
+	}
+
+	delimited := field("arepeated_delimited", 6, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".protoyaml.test.editions.Message", &descriptorpb.FeatureSet{MessageEncoding: descriptorpb.FeatureSet_DELIMITED.Enum()})
+	delimited.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("protoyaml/test/editions.proto"),
+		Package: proto.String("protoyaml.test.editions"),
+		Syntax:  proto.String("editions"),
+		Edition: descriptorpb.Edition_EDITION_2023.Enum(),
+		EnumType: []*descriptorpb.EnumDescriptorProto{
+			enum("Closed", descriptorpb.FeatureSet_CLOSED),
+			enum("Open", descriptorpb.FeatureSet_OPEN),
+		},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Message"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("implicit", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32, "", &descriptorpb.FeatureSet{FieldPresence: descriptorpb.FeatureSet_IMPLICIT.Enum()}),
+					field("explicit", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32, "", nil),
+					field("closed", 3, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".protoyaml.test.editions.Closed", nil),
+					field("open", 4, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".protoyaml.test.editions.Open", nil),
+					field("delimited", 5, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".protoyaml.test.editions.Message", &descriptorpb.FeatureSet{MessageEncoding: descriptorpb.FeatureSet_DELIMITED.Enum()}),
+					delimited,
+				},
+			},
+			{
+				Name: proto.String("Required"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("required", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32, "", &descriptorpb.FeatureSet{FieldPresence: descriptorpb.FeatureSet_LEGACY_REQUIRED.Enum()}),
+					field("optional", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32, "", nil),
+				},
+			},
+		},
+	}
+	fd, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("protodesc.NewFile failed: %v", err)
+	}
+	return fd
+}
+
 func parseYAML(s string) (*Decoder, *yaml.Node, error) {
 	d := NewDecoder(strings.NewReader(s))
 	var n yaml.Node
@@ -0,0 +1,203 @@
+package protoyaml
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+)
+
+// A ChangeKind says how a value differs.
+type ChangeKind int
+
+const (
+	// ChangeAdded is a value only set in the new message.
+	ChangeAdded ChangeKind = iota
+
+	// ChangeRemoved is a value only set in the old message.
+	ChangeRemoved
+
+	// ChangeModified is a value set to different values.
+	ChangeModified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	default:
+		return fmt.Sprintf("ChangeKind(%d)", int(k))
+	}
+}
+
+// A Change is a difference between two messages, found by Diff.
+type Change struct {
+	Kind ChangeKind
+
+	// Path is the field path of the value.
+	Path string
+
+	// Field describes the values. For list elements, it is the list
+	// field, and for map entries, it is the map value field.
+	Field protoreflect.FieldDescriptor
+
+	// Old and New are the values. Old is invalid for an added value,
+	// and New for a removed value.
+	Old, New protoreflect.Value
+}
+
+// String returns a one-line description, like `~ a.b: 1 -> 2`, with
+// values in YAML flow style.
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("+ %s: %s", c.Path, formatValue(c.Field, c.New))
+	case ChangeRemoved:
+		return fmt.Sprintf("- %s: %s", c.Path, formatValue(c.Field, c.Old))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Path, formatValue(c.Field, c.Old), formatValue(c.Field, c.New))
+	}
+}
+
+// formatValue returns a singular value as YAML flow text.
+func formatValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	n, err := NewEncoder(nil).encodeSingular(fd, v)
+	if err != nil {
+		return fmt.Sprintf("<%v>", err)
+	}
+	n.Style |= yaml.FlowStyle
+	bs, err := yaml.Marshal(n)
+	if err != nil {
+		return fmt.Sprintf("<%v>", err)
+	}
+	return strings.TrimSuffix(string(bs), "\n")
+}
+
+// Diff returns the differences between two messages of the same type,
+// in field declaration order. Since the messages are compared after
+// decoding, the YAML spelling of values doesn't matter, e.g. enum names
+// and numbers are equal. An unset field is equal to one set to its
+// default value. Nested messages are compared field by field. Lists
+// are compared element by element, and maps by key, where a missing
+// element or entry is added or removed as a whole.
+func Diff(old, new protoreflect.ProtoMessage) ([]Change, error) {
+	om, nm := old.ProtoReflect(), new.ProtoReflect()
+	if om.Descriptor().FullName() != nm.Descriptor().FullName() {
+		return nil, fmt.Errorf("protoyaml: cannot compare a %s with a %s", om.Descriptor().FullName(), nm.Descriptor().FullName())
+	}
+	var cs []Change
+	diffMessage(&cs, "", om, nm)
+	return cs, nil
+}
+
+// diffMessage appends the changes between two messages.
+func diffMessage(cs *[]Change, path string, old, new protoreflect.Message) {
+	fds := populatedFields(old)
+	for _, fd := range populatedFields(new) {
+		if !old.Has(fd) {
+			fds = append(fds, fd)
+		}
+	}
+	sortFields(fds)
+
+	for _, fd := range fds {
+		fpath := fieldPath(path, fd)
+		switch {
+		case fd.IsMap():
+			diffMap(cs, fpath, fd, old.Get(fd).Map(), new.Get(fd).Map())
+		case fd.IsList():
+			diffList(cs, fpath, fd, old.Get(fd).List(), new.Get(fd).List())
+		case isMessageKind(fd) && !isKnownType(fd.Message()):
+			diffValue(cs, fpath, fd, old.Get(fd), new.Get(fd))
+		default:
+			// Unset fields have their default value.
+			ov, nv := old.Get(fd), new.Get(fd)
+			if ov.Equal(nv) {
+				continue
+			}
+			if !old.Has(fd) {
+				ov = protoreflect.Value{}
+			}
+			if !new.Has(fd) {
+				nv = protoreflect.Value{}
+			}
+			diffValue(cs, fpath, fd, ov, nv)
+		}
+	}
+}
+
+// sortFields sorts fields like populatedFields: in declaration order,
+// followed by extensions in number order.
+func sortFields(fds []protoreflect.FieldDescriptor) {
+	order := func(fd protoreflect.FieldDescriptor) int {
+		if fd.IsExtension() {
+			return fd.ContainingMessage().Fields().Len() + int(fd.Number())
+		}
+		return fd.Index()
+	}
+	sort.Slice(fds, func(i, j int) bool { return order(fds[i]) < order(fds[j]) })
+}
+
+// diffList appends the changes between two lists, element by element.
+func diffList(cs *[]Change, path string, fd protoreflect.FieldDescriptor, old, new protoreflect.List) {
+	for i := 0; i < old.Len() || i < new.Len(); i++ {
+		var ov, nv protoreflect.Value
+		if i < old.Len() {
+			ov = old.Get(i)
+		}
+		if i < new.Len() {
+			nv = new.Get(i)
+		}
+		diffValue(cs, listPath(path, i), fd, ov, nv)
+	}
+}
+
+// diffMap appends the changes between two maps, in key order.
+func diffMap(cs *[]Change, path string, fd protoreflect.FieldDescriptor, old, new protoreflect.Map) {
+	var keys []protoreflect.MapKey
+	old.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+		keys = append(keys, k)
+		return true
+	})
+	new.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+		if !old.Has(k) {
+			keys = append(keys, k)
+		}
+		return true
+	})
+	sortMapKeys(keys)
+
+	for _, k := range keys {
+		var ov, nv protoreflect.Value
+		if old.Has(k) {
+			ov = old.Get(k)
+		}
+		if new.Has(k) {
+			nv = new.Get(k)
+		}
+		diffValue(cs, mapPath(path, k), fd.MapValue(), ov, nv)
+	}
+}
+
+// diffValue appends the changes between two singular values, which
+// are invalid if not set. Messages set on both sides are compared
+// field by field.
+func diffValue(cs *[]Change, path string, fd protoreflect.FieldDescriptor, old, new protoreflect.Value) {
+	switch {
+	case !old.IsValid() && !new.IsValid():
+	case old.IsValid() && new.IsValid() && isMessageKind(fd) && !isKnownType(fd.Message()):
+		diffMessage(cs, path, old.Message(), new.Message())
+	case !old.IsValid():
+		*cs = append(*cs, Change{Kind: ChangeAdded, Path: path, Field: fd, New: new})
+	case !new.IsValid():
+		*cs = append(*cs, Change{Kind: ChangeRemoved, Path: path, Field: fd, Old: old})
+	case !old.Equal(new):
+		*cs = append(*cs, Change{Kind: ChangeModified, Path: path, Field: fd, Old: old, New: new})
+	}
+}
@@ -0,0 +1,103 @@
+package protoyaml
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tommie/protoyaml-go/internal/testproto"
+)
+
+func TestDiff(t *testing.T) {
+	tsts := []struct {
+		Name     string
+		Old, New string
+		Want     []string
+	}{
+		{
+			Name: "equal",
+			Old:  "anenum: ONE\nastring_int32_map: {a: 1, b: 2}\namessage: {}\n",
+			New:  "astring_int32_map: {b: 2, a: 1}\nanenum: 1\nanint32: 0\n",
+		},
+		{
+			Name: "scalars",
+			Old:  "anint32: 1\nastring: a\n",
+			New:  "anint32: 2\nanenum: TWO\n",
+			Want: []string{
+				"~ anint32: 1 -> 2",
+				"- astring: a",
+				"+ anenum: TWO",
+			},
+		},
+		{
+			Name: "nested",
+			Old:  "amessage: {anint32: 1}\n",
+			New:  "amessage: {anint32: 1, amessage: {abool: true}}\n",
+			Want: []string{"+ amessage.amessage.abool: true"},
+		},
+		{
+			Name: "list",
+			Old:  "arepeated_string: [a, b, c]\n",
+			New:  "arepeated_string: [a, x]\n",
+			Want: []string{
+				`~ arepeated_string[1]: b -> x`,
+				`- arepeated_string[2]: c`,
+			},
+		},
+		{
+			Name: "map",
+			Old:  "astring_message_map: {a: {anint32: 1}, b: {}}\n",
+			New:  "astring_message_map: {a: {anint32: 2}, c: {astring: x}}\n",
+			Want: []string{
+				`~ astring_message_map["a"].anint32: 1 -> 2`,
+				`- astring_message_map["b"]: {}`,
+				`+ astring_message_map["c"]: {astring: x}`,
+			},
+		},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Name, func(t *testing.T) {
+			var old, new testproto.Message
+			if err := Unmarshal([]byte(tst.Old), &old); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if err := Unmarshal([]byte(tst.New), &new); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+
+			cs, err := Diff(&old, &new)
+			if err != nil {
+				t.Fatalf("Diff failed: %v", err)
+			}
+			var got []string
+			for _, c := range cs {
+				got = append(got, c.String())
+			}
+			if diff := cmp.Diff(tst.Want, got); diff != "" {
+				t.Errorf("Diff: +got, -want:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDiffKnown(t *testing.T) {
+	var old, new testproto.Known
+	if err := Unmarshal([]byte("aduration: 1m\n"), &old); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if err := Unmarshal([]byte("aduration: 60s\n"), &new); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if cs, err := Diff(&old, &new); err != nil || len(cs) != 0 {
+		t.Errorf("Diff: got %v, %v, want no changes", cs, err)
+	}
+
+	new.Aduration.Seconds = 90
+	cs, err := Diff(&old, &new)
+	if err != nil {
+		t.Fatalf("Diff failed: %v", err)
+	}
+	if len(cs) != 1 || cs[0].String() != "~ aduration: 60s -> 90s" {
+		t.Errorf("Diff: got %v, want a changed duration", cs)
+	}
+}
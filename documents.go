@@ -0,0 +1,132 @@
+package protoyaml
+
+import (
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// A DocumentError describes a failure to decode one document in a
+// multi-document stream.
+type DocumentError struct {
+	// Index is the zero-based index of the document in the stream.
+	Index int
+
+	// Line is the line the document starts on, or zero if the
+	// document could not be parsed.
+	Line int
+
+	Err error
+}
+
+func (e *DocumentError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("document %d: %v", e.Index+1, e.Err)
+	}
+	return fmt.Sprintf("document %d (line %d): %v", e.Index+1, e.Line, e.Err)
+}
+
+func (e *DocumentError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeAll decodes all remaining documents. Each message is
+// allocated by calling newMessage. If newMessage is nil, the type is
+// selected by the document, as in DecodeNew. Errors are returned as
+// *DocumentError.
+func (d *Decoder) DecodeAll(newMessage func() proto.Message) ([]proto.Message, error) {
+	var ms []proto.Message
+	it := d.Documents(newMessage)
+	for it.Next() {
+		ms = append(ms, it.Message())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return ms, nil
+}
+
+// A DocumentIterator iterates over the remaining documents of a
+// Decoder. Use Next to advance, and Err to check for failure once Next
+// returns false:
+//
+//	it := d.Documents(func() proto.Message { return &mypb.Config{} })
+//	for it.Next() {
+//		use(it.Message())
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+type DocumentIterator struct {
+	d          *Decoder
+	newMessage func() proto.Message
+
+	m     proto.Message
+	index int
+	line  int
+	err   error
+}
+
+// Documents returns an iterator over the remaining documents. Each
+// message is allocated by calling newMessage. If newMessage is nil,
+// the type is selected by the document, as in DecodeNew.
+func (d *Decoder) Documents(newMessage func() proto.Message) *DocumentIterator {
+	return &DocumentIterator{d: d, newMessage: newMessage, index: -1}
+}
+
+// Next decodes the next document. Returns false if there are no more
+// documents, or decoding failed.
+func (it *DocumentIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	it.m = nil
+	it.line = 0
+	n, err := it.d.nextDocument()
+	if err == io.EOF {
+		return false
+	}
+	it.index++
+	if err != nil {
+		it.err = &DocumentError{Index: it.index, Err: err}
+		return false
+	}
+	it.line = n.Line
+
+	var m proto.Message
+	if it.newMessage != nil {
+		m = it.newMessage()
+		err = it.d.decodeRoot(n, m)
+	} else {
+		m, err = it.d.decodeNewRoot(n)
+	}
+	if err != nil {
+		it.err = &DocumentError{Index: it.index, Line: it.line, Err: err}
+		return false
+	}
+	it.m = m
+	return true
+}
+
+// Message returns the message decoded by the last call to Next.
+func (it *DocumentIterator) Message() proto.Message {
+	return it.m
+}
+
+// Index returns the zero-based index of the current document.
+func (it *DocumentIterator) Index() int {
+	return it.index
+}
+
+// Line returns the line the current document starts on.
+func (it *DocumentIterator) Line() int {
+	return it.line
+}
+
+// Err returns the error that stopped iteration, if any. It is nil if
+// all documents were decoded. Errors are returned as *DocumentError.
+func (it *DocumentIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,113 @@
+package protoyaml
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/tommie/protoyaml-go/internal/testproto"
+)
+
+func ExampleDecoder_DecodeAll() {
+	d := NewDecoder(strings.NewReader(`astring: hello
+---
+astring: world`))
+
+	ms, err := d.DecodeAll(func() proto.Message { return &testproto.Message{} })
+	if err != nil {
+		panic(err)
+	}
+	for _, m := range ms {
+		fmt.Println(m.(*testproto.Message).Astring)
+	}
+	// Output:
+	// hello
+	// world
+}
+
+func TestDecoderDecodeAll(t *testing.T) {
+	t.Run("factory", func(t *testing.T) {
+		d := NewDecoder(strings.NewReader(`astring: hello
+---
+anint32: 42`))
+
+		got, err := d.DecodeAll(func() proto.Message { return &testproto.Message{} })
+		if err != nil {
+			t.Fatalf("DecodeAll failed: %v", err)
+		}
+
+		want := []proto.Message{&testproto.Message{Astring: "hello"}, &testproto.Message{Anint32: 42}}
+		if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+			t.Errorf("DecodeAll: +got, -want:\n%s", diff)
+		}
+	})
+
+	t.Run("typed", func(t *testing.T) {
+		d := NewDecoder(strings.NewReader(`"@type": type.googleapis.com/protoyaml.test.Message
+astring: hello`))
+
+		got, err := d.DecodeAll(nil)
+		if err != nil {
+			t.Fatalf("DecodeAll failed: %v", err)
+		}
+
+		want := []proto.Message{&testproto.Message{Astring: "hello"}}
+		if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+			t.Errorf("DecodeAll: +got, -want:\n%s", diff)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		d := NewDecoder(strings.NewReader(`astring: hello
+---
+anint32: 42
+---
+
+unknown: 42`))
+
+		_, err := d.DecodeAll(func() proto.Message { return &testproto.Message{} })
+		var derr *DocumentError
+		if !errors.As(err, &derr) {
+			t.Fatalf("DecodeAll: got %v, want a DocumentError", err)
+		}
+		if derr.Index != 2 || derr.Line != 6 {
+			t.Errorf("DecodeAll: got index %d, line %d, want 2, 6", derr.Index, derr.Line)
+		}
+		if !strings.HasPrefix(err.Error(), "document 3 (line 6): ") {
+			t.Errorf("DecodeAll: got %q, want document and line prefix", err.Error())
+		}
+	})
+}
+
+func TestDocumentIterator(t *testing.T) {
+	d := NewDecoder(strings.NewReader(`astring: hello
+---
+# A comment.
+anint32: 42`))
+
+	type doc struct {
+		Index, Line int
+		Message     proto.Message
+	}
+	var got []doc
+	it := d.Documents(func() proto.Message { return &testproto.Message{} })
+	for it.Next() {
+		got = append(got, doc{it.Index(), it.Line(), it.Message()})
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Err failed: %v", err)
+	}
+
+	want := []doc{
+		{0, 1, &testproto.Message{Astring: "hello"}},
+		{1, 4, &testproto.Message{Anint32: 42}},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("Next: +got, -want:\n%s", diff)
+	}
+}
@@ -0,0 +1,61 @@
+package protoyaml
+
+import (
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// A DynamicDecoder decodes YAML documents as dynamicpb messages, for
+// when the schema is only known at runtime. Any messages and
+// extension fields are resolved against the same descriptor set,
+// rather than protoregistry.GlobalTypes.
+type DynamicDecoder struct {
+	d  *Decoder
+	md protoreflect.MessageDescriptor
+}
+
+// NewDynamicDecoder creates a new decoder reading from the given
+// stream of YAML text. Each document is decoded as the message called
+// name, which must be defined in fds. The set must be self-contained,
+// i.e. include all dependencies.
+func NewDynamicDecoder(r io.Reader, fds *descriptorpb.FileDescriptorSet, name protoreflect.FullName) (*DynamicDecoder, error) {
+	files, err := protodesc.NewFiles(fds)
+	if err != nil {
+		return nil, fmt.Errorf("protoyaml: invalid descriptor set: %w", err)
+	}
+	desc, err := files.FindDescriptorByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("protoyaml: unknown message %s: %w", name, err)
+	}
+	md, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("protoyaml: %s is not a message", name)
+	}
+
+	types := dynamicpb.NewTypes(files)
+	d := NewDecoder(r)
+	d.MessageTypeResolver(types)
+	d.ExtensionTypeResolver(types)
+
+	return &DynamicDecoder{d: d, md: md}, nil
+}
+
+// Descriptor returns the descriptor of the messages produced by Decode.
+func (d *DynamicDecoder) Descriptor() protoreflect.MessageDescriptor {
+	return d.md
+}
+
+// Decode decodes the next document as a new message. Returns io.EOF
+// if there are no more documents.
+func (d *DynamicDecoder) Decode() (*dynamicpb.Message, error) {
+	m := dynamicpb.NewMessage(d.md)
+	if err := d.d.Decode(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,160 @@
+package protoyaml
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/tommie/protoyaml-go/internal/testproto"
+)
+
+func TestDynamicDecoderDecode(t *testing.T) {
+	fds := fileDescriptorSet(testproto.File_internal_testproto_test_proto)
+
+	t.Run("multipleDocuments", func(t *testing.T) {
+		d, err := NewDynamicDecoder(strings.NewReader(`astring: hello
+---
+anint32: 42`), fds, "protoyaml.test.Message")
+		if err != nil {
+			t.Fatalf("NewDynamicDecoder failed: %v", err)
+		}
+
+		var got []*testproto.Message
+		for {
+			m, err := d.Decode()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			var tm testproto.Message
+			convertMessage(t, m, &tm)
+			got = append(got, &tm)
+		}
+
+		want := []*testproto.Message{{Astring: "hello"}, {Anint32: 42}}
+		if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+			t.Errorf("Decode: +got, -want:\n%s", diff)
+		}
+	})
+
+	t.Run("known", func(t *testing.T) {
+		d, err := NewDynamicDecoder(strings.NewReader(`anany: {"@type": "type.googleapis.com/protoyaml.test.Message", astring: hello}
+aduration: 42s`), fds, "protoyaml.test.Known")
+		if err != nil {
+			t.Fatalf("NewDynamicDecoder failed: %v", err)
+		}
+
+		m, err := d.Decode()
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		var got testproto.Known
+		convertMessage(t, m, &got)
+
+		anany, err := anypb.New(&testproto.Message{Astring: "hello"})
+		if err != nil {
+			t.Fatalf("anypb.New failed: %v", err)
+		}
+		want := &testproto.Known{Anany: anany, Aduration: durationpb.New(42 * time.Second)}
+		if diff := cmp.Diff(want, &got, protocmp.Transform()); diff != "" {
+			t.Errorf("Decode: +got, -want:\n%s", diff)
+		}
+	})
+
+	t.Run("extension", func(t *testing.T) {
+		d, err := NewDynamicDecoder(strings.NewReader(`{anint32: 42, "[protoyaml.test.ext.astring]": hello}`), extensionDescriptorSet(), "protoyaml.test.ext.Extendable")
+		if err != nil {
+			t.Fatalf("NewDynamicDecoder failed: %v", err)
+		}
+
+		m, err := d.Decode()
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+
+		var got []string
+		m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+			got = append(got, string(fd.FullName())+"="+v.String())
+			return true
+		})
+		sort.Strings(got)
+		want := []string{"protoyaml.test.ext.Extendable.anint32=42", "protoyaml.test.ext.astring=hello"}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Decode: +got, -want:\n%s", diff)
+		}
+	})
+
+	t.Run("unknownMessage", func(t *testing.T) {
+		if _, err := NewDynamicDecoder(strings.NewReader(``), fds, "protoyaml.test.Missing"); err == nil {
+			t.Errorf("NewDynamicDecoder: got nil error, want unknown message")
+		}
+	})
+}
+
+// fileDescriptorSet returns a set containing the given files and their
+// transitive dependencies.
+func fileDescriptorSet(fds ...protoreflect.FileDescriptor) *descriptorpb.FileDescriptorSet {
+	var out descriptorpb.FileDescriptorSet
+	seen := map[string]bool{}
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		for i := 0; i < fd.Imports().Len(); i++ {
+			add(fd.Imports().Get(i).FileDescriptor)
+		}
+		out.File = append(out.File, protodesc.ToFileDescriptorProto(fd))
+	}
+	for _, fd := range fds {
+		add(fd)
+	}
+	return &out
+}
+
+// extensionDescriptorSet returns a proto2 file with an extendable
+// message and one extension field.
+func extensionDescriptorSet() *descriptorpb.FileDescriptorSet {
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	return &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{{
+		Name:    proto.String("protoyaml/test/ext.proto"),
+		Package: proto.String("protoyaml.test.ext"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Extendable"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("anint32"), Number: proto.Int32(1), Label: optional, Type: descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum()},
+			},
+			ExtensionRange: []*descriptorpb.DescriptorProto_ExtensionRange{{Start: proto.Int32(100), End: proto.Int32(200)}},
+		}},
+		Extension: []*descriptorpb.FieldDescriptorProto{
+			{Name: proto.String("astring"), Number: proto.Int32(100), Label: optional, Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Extendee: proto.String(".protoyaml.test.ext.Extendable")},
+		},
+	}}}
+}
+
+// convertMessage copies a message into another of the same full name,
+// e.g. from a dynamic message to a generated one.
+func convertMessage(t *testing.T, from, to proto.Message) {
+	t.Helper()
+
+	bs, err := proto.Marshal(from)
+	if err != nil {
+		t.Fatalf("proto.Marshal failed: %v", err)
+	}
+	if err := proto.Unmarshal(bs, to); err != nil {
+		t.Fatalf("proto.Unmarshal failed: %v", err)
+	}
+}
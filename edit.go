@@ -0,0 +1,430 @@
+package protoyaml
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+)
+
+// A Document is a decoded YAML document, kept for editing. After the
+// message has been modified, Encoder.EncodeDocument writes the
+// document back with only the changed values rewritten, so comments,
+// key order, anchors, quoting and indentation of everything else are
+// kept. Since yaml.v3 writes the whole document, blank lines are
+// dropped, and spacing within lines is normalised:
+//
+//	doc, err := d.DecodeDocument(&cfg)
+//	...
+//	cfg.Version++
+//	err = e.EncodeDocument(doc, &cfg)
+type Document struct {
+	node *yaml.Node
+	m    protoreflect.Message
+	refs bool
+}
+
+// Node returns the document node. It is updated in place by
+// Encoder.UpdateDocument.
+func (doc *Document) Node() *yaml.Node {
+	return doc.node
+}
+
+// DocumentIndent returns how many spaces the nested block collections
+// of a parsed document are indented by, for yaml.Encoder.SetIndent, so
+// re-encoding the document keeps its indentation. Returns 4, the
+// yaml.v3 default, if nothing is nested.
+func DocumentIndent(n *yaml.Node) int {
+	var walk func(n *yaml.Node) int
+	walk = func(n *yaml.Node) int {
+		if n.Kind == yaml.MappingNode && n.Style&yaml.FlowStyle == 0 {
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				kn, v := n.Content[i], n.Content[i+1]
+				isBlock := (v.Kind == yaml.MappingNode || v.Kind == yaml.SequenceNode) && v.Style&yaml.FlowStyle == 0
+				if isBlock && v.Line > kn.Line && v.Column > kn.Column {
+					return v.Column - kn.Column
+				}
+			}
+		}
+		for _, c := range n.Content {
+			if indent := walk(c); indent > 0 {
+				return indent
+			}
+		}
+		return 0
+	}
+	if indent := walk(n); indent > 0 {
+		return indent
+	}
+	return 4
+}
+
+// DecodeDocument decodes the next document as a message, like Decode,
+// and returns the document for editing. Returns io.EOF if there are no
+// more documents. Documents cannot be edited in patch mode.
+func (d *Decoder) DecodeDocument(v interface{}) (*Document, error) {
+	if v == nil {
+		return nil, fmt.Errorf("protoyaml: nil destination message")
+	}
+	if d.patch {
+		return nil, fmt.Errorf("protoyaml: cannot edit documents in patch mode")
+	}
+	n := &yaml.Node{}
+	if err := d.yd.Decode(n); err != nil {
+		return nil, err
+	}
+	root, err := documentRoot(n)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.decodeRoot(root, v); err != nil {
+		return nil, err
+	}
+	return &Document{node: n, m: cloneMessage(messageOf(v)), refs: d.refFS != nil}, nil
+}
+
+// EncodeDocument updates doc to hold v, see UpdateDocument, and writes
+// it as the next document. If it is the first document of the stream,
+// the stream is indented like doc, see DocumentIndent.
+func (e *Encoder) EncodeDocument(doc *Document, v interface{}) error {
+	if e.ye == nil {
+		return fmt.Errorf("protoyaml: the encoder has no writer")
+	}
+	if err := e.UpdateDocument(doc, v); err != nil {
+		return err
+	}
+	if !e.started {
+		// yaml.v3 only uses the indentation set before the first
+		// document.
+		e.ye.SetIndent(DocumentIndent(doc.node))
+		e.started = true
+	}
+	return e.ye.Encode(doc.node)
+}
+
+// UpdateDocument rewrites the nodes of doc whose values differ in v,
+// which must be the message type the document was decoded as. New
+// fields are added last in their mapping, and cleared fields are
+// removed. Changed values from $ref mappings are written inline.
+//
+// It is an error to change a value with an anchor, since that would
+// also change its aliases, or to clear a field set through a merge
+// key. The document may be partially updated on error.
+func (e *Encoder) UpdateDocument(doc *Document, v interface{}) error {
+	m := messageOf(v)
+	if m == nil {
+		return fmt.Errorf("protoyaml: cannot marshal a %T", v)
+	}
+	if got, want := m.Descriptor().FullName(), doc.m.Descriptor().FullName(); got != want {
+		return fmt.Errorf("protoyaml: cannot update a %s document with a %s", want, got)
+	}
+
+	root := doc.node
+	if root.Kind == yaml.DocumentNode {
+		root = root.Content[0]
+	}
+	u := updater{e: e, refs: doc.refs, aliases: aliasCache{}}
+	if !u.editable(root, yaml.MappingNode) || isKnownType(m.Descriptor()) {
+		return fmt.Errorf("protoyaml: cannot update the document root in place")
+	}
+	if err := u.updateMessage(root, doc.m, m, ""); err != nil {
+		return err
+	}
+	doc.m = cloneMessage(m)
+	return nil
+}
+
+// An updater rewrites node trees, comparing old and new values.
+type updater struct {
+	e       *Encoder
+	refs    bool
+	aliases aliasCache
+}
+
+// updateMessage updates an editable mapping holding old, so it holds
+// new.
+func (u *updater) updateMessage(n *yaml.Node, old, new protoreflect.Message, path string) error {
+	fds := populatedFields(new)
+	for _, fd := range populatedFields(old) {
+		if !new.Has(fd) {
+			fds = append(fds, fd)
+		}
+	}
+
+	var removed []int
+	for _, fd := range fds {
+		oh, nh := old.Has(fd), new.Has(fd)
+		if oh == nh && (!nh || old.Get(fd).Equal(new.Get(fd))) {
+			continue
+		}
+
+		fpath := fieldPath(path, fd)
+		i := u.localField(n, fd)
+		switch {
+		case !nh:
+			if i < 0 {
+				return fmt.Errorf("protoyaml: %s: cannot clear a field set through a merge key", fpath)
+			}
+			removed = append(removed, i-1)
+
+		case i < 0:
+			vn, err := u.e.encodeField(fd, new.Get(fd))
+			if err != nil {
+				return err
+			}
+			kn := stringNode(fieldKey(fd))
+			if u.e.comments {
+				addFieldComments(kn, vn, fd)
+			}
+			n.Content = append(n.Content, kn, vn)
+
+		default:
+			ov := protoreflect.Value{}
+			if oh {
+				ov = old.Get(fd)
+			}
+			vn, err := u.updateField(n.Content[i], fd, ov, new.Get(fd), fpath)
+			if err != nil {
+				return err
+			}
+			n.Content[i] = vn
+		}
+	}
+	n.Content = withoutPairs(n.Content, removed)
+	return nil
+}
+
+// localField returns the index of the value node of a field in a
+// mapping, or -1 if it is not a key of the mapping itself. Like in
+// decoding, the last key wins.
+func (u *updater) localField(n *yaml.Node, fd protoreflect.FieldDescriptor) int {
+	idx := -1
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		kn := n.Content[i]
+		if isMergeKey(kn) {
+			continue
+		}
+		if kn.Value == fieldKey(fd) || (!fd.IsExtension() && u.aliases.find(fd.ContainingMessage(), kn.Value) == fd) {
+			idx = i + 1
+		}
+	}
+	return idx
+}
+
+// withoutPairs returns the content of a mapping, without the key-value
+// pairs whose keys are at the given indices.
+func withoutPairs(content []*yaml.Node, keys []int) []*yaml.Node {
+	if len(keys) == 0 {
+		return content
+	}
+	skip := make(map[int]bool, len(keys))
+	for _, i := range keys {
+		skip[i] = true
+	}
+	var out []*yaml.Node
+	for i := 0; i+1 < len(content); i += 2 {
+		if !skip[i] {
+			out = append(out, content[i], content[i+1])
+		}
+	}
+	return out
+}
+
+// updateField updates the value node of a field, which may be a map or
+// a list, and returns the node to use. The old value is invalid if the
+// field was not set.
+func (u *updater) updateField(n *yaml.Node, fd protoreflect.FieldDescriptor, ov, nv protoreflect.Value, path string) (*yaml.Node, error) {
+	switch {
+	case fd.IsMap():
+		if !ov.IsValid() || !u.editable(n, yaml.MappingNode) {
+			return u.replaceField(n, fd, nv, path)
+		}
+		return n, u.updateMap(n, fd, ov.Map(), nv.Map(), path)
+
+	case fd.IsList():
+		if !ov.IsValid() || !u.editable(n, yaml.SequenceNode) || len(n.Content) != ov.List().Len() {
+			return u.replaceField(n, fd, nv, path)
+		}
+		return n, u.updateList(n, fd, ov.List(), nv.List(), path)
+
+	default:
+		return u.updateSingular(n, fd, ov, nv, path)
+	}
+}
+
+// updateList updates an editable sequence, element by element.
+func (u *updater) updateList(n *yaml.Node, fd protoreflect.FieldDescriptor, ol, nl protoreflect.List, path string) error {
+	for i := 0; i < nl.Len(); i++ {
+		if i >= len(n.Content) {
+			en, err := u.e.encodeSingular(fd, nl.Get(i))
+			if err != nil {
+				return err
+			}
+			n.Content = append(n.Content, en)
+			continue
+		}
+		if ol.Get(i).Equal(nl.Get(i)) {
+			continue
+		}
+		en, err := u.updateSingular(n.Content[i], fd, ol.Get(i), nl.Get(i), listPath(path, i))
+		if err != nil {
+			return err
+		}
+		n.Content[i] = en
+	}
+	if len(n.Content) > nl.Len() {
+		n.Content = n.Content[:nl.Len()]
+	}
+	return nil
+}
+
+// updateMap updates an editable mapping holding a map field, entry by
+// entry.
+func (u *updater) updateMap(n *yaml.Node, fd protoreflect.FieldDescriptor, om, nm protoreflect.Map, path string) error {
+	kd := NewDecoder(nil)
+	local := map[interface{}]int{}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if isMergeKey(n.Content[i]) {
+			continue
+		}
+		kv, err := kd.decodeValue(fd.MapKey(), n.Content[i])
+		if err != nil {
+			return err
+		}
+		local[kv.Interface()] = i + 1
+	}
+
+	var removed []int
+	var err error
+	om.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+		if nm.Has(k) {
+			return true
+		}
+		i, ok := local[k.Interface()]
+		if !ok {
+			err = fmt.Errorf("protoyaml: %s: cannot remove a map entry set through a merge key", mapPath(path, k))
+			return false
+		}
+		removed = append(removed, i-1)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	keys := make([]protoreflect.MapKey, 0, nm.Len())
+	nm.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+		keys = append(keys, k)
+		return true
+	})
+	sortMapKeys(keys)
+	for _, k := range keys {
+		ov := om.Get(k)
+		if om.Has(k) && ov.Equal(nm.Get(k)) {
+			continue
+		}
+
+		i, ok := local[k.Interface()]
+		if !ok {
+			kn, err := u.e.encodeValue(fd.MapKey(), k.Value())
+			if err != nil {
+				return err
+			}
+			vn, err := u.e.encodeSingular(fd.MapValue(), nm.Get(k))
+			if err != nil {
+				return err
+			}
+			n.Content = append(n.Content, kn, vn)
+			continue
+		}
+		vn, err := u.updateSingular(n.Content[i], fd.MapValue(), ov, nm.Get(k), mapPath(path, k))
+		if err != nil {
+			return err
+		}
+		n.Content[i] = vn
+	}
+	n.Content = withoutPairs(n.Content, removed)
+	return nil
+}
+
+// updateSingular updates the node of a message or a non-compound
+// value, and returns the node to use. Messages are updated in place
+// if possible.
+func (u *updater) updateSingular(n *yaml.Node, fd protoreflect.FieldDescriptor, ov, nv protoreflect.Value, path string) (*yaml.Node, error) {
+	if isMessageKind(fd) && ov.IsValid() && !isKnownType(fd.Message()) && u.editable(n, yaml.MappingNode) {
+		return n, u.updateMessage(n, ov.Message(), nv.Message(), path)
+	}
+	nn, err := u.e.encodeSingular(fd, nv)
+	if err != nil {
+		return nil, err
+	}
+	return replaceNode(n, nn, path)
+}
+
+// replaceField returns a new node for the value of a field, replacing
+// n.
+func (u *updater) replaceField(n *yaml.Node, fd protoreflect.FieldDescriptor, nv protoreflect.Value, path string) (*yaml.Node, error) {
+	nn, err := u.e.encodeField(fd, nv)
+	if err != nil {
+		return nil, err
+	}
+	return replaceNode(n, nn, path)
+}
+
+// editable returns true if n is a plain node of the given kind, which
+// can be updated in place.
+func (u *updater) editable(n *yaml.Node, kind yaml.Kind) bool {
+	if n.Kind != kind || n.Anchor != "" {
+		return false
+	}
+	switch kind {
+	case yaml.MappingNode:
+		if u.refs && len(n.Content) >= 2 && n.Content[0].Value == RefKey {
+			return false
+		}
+		return n.ShortTag() == "!!map"
+	case yaml.SequenceNode:
+		return n.ShortTag() == "!!seq"
+	default:
+		return false
+	}
+}
+
+// replaceNode returns nn to replace n, keeping the comments of n, and
+// the quoting and flow styles where they still apply.
+func replaceNode(n, nn *yaml.Node, path string) (*yaml.Node, error) {
+	if n.Anchor != "" {
+		return nil, fmt.Errorf("protoyaml: %s: cannot change the value of anchor &%s", path, n.Anchor)
+	}
+	nn.HeadComment = n.HeadComment
+	nn.LineComment = n.LineComment
+	nn.FootComment = n.FootComment
+	switch {
+	case n.Kind == yaml.ScalarNode && nn.Kind == yaml.ScalarNode:
+		if n.ShortTag() == "!!str" && nn.Tag == "!!str" && nn.Style == 0 {
+			nn.Style = n.Style
+		}
+	case n.Kind == nn.Kind:
+		nn.Style |= n.Style & yaml.FlowStyle
+	}
+	return nn, nil
+}
+
+// messageOf returns the message of a proto.Message, or a
+// protoreflect.Message, or nil.
+func messageOf(v interface{}) protoreflect.Message {
+	switch m := v.(type) {
+	case protoreflect.Message:
+		return m
+	case protoreflect.ProtoMessage:
+		return m.ProtoReflect()
+	default:
+		return nil
+	}
+}
+
+// cloneMessage returns a deep copy of m.
+func cloneMessage(m protoreflect.Message) protoreflect.Message {
+	return proto.Clone(m.Interface()).ProtoReflect()
+}
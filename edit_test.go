@@ -0,0 +1,281 @@
+package protoyaml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tommie/protoyaml-go/internal/testproto"
+)
+
+func TestEncoderEncodeDocument(t *testing.T) {
+	tsts := []struct {
+		Name string
+		In   string
+		Edit func(*testproto.Message)
+		Want string
+	}{
+		{
+			Name: "unchanged",
+			In: `# Head.
+astring: 'hello' # Line.
+anenum: 1
+`,
+			Edit: func(m *testproto.Message) {},
+			Want: `# Head.
+astring: 'hello' # Line.
+anenum: 1
+`,
+		},
+		{
+			Name: "scalar",
+			In: `anint32: 1 # The version.
+astring: 'hello'
+`,
+			Edit: func(m *testproto.Message) {
+				m.Anint32++
+				m.Astring = "world"
+			},
+			Want: `anint32: 2 # The version.
+astring: 'world'
+`,
+		},
+		{
+			Name: "add",
+			In:   "astring: hello\n",
+			Edit: func(m *testproto.Message) {
+				m.Amessage = &testproto.Message{Anint32: 1}
+			},
+			Want: `astring: hello
+amessage:
+    anint32: 1
+`,
+		},
+		{
+			Name: "indent",
+			In: `amessage:
+  anint32: 1
+`,
+			Edit: func(m *testproto.Message) {
+				m.Amessage.Anint32 = 2
+				m.AstringMessageMap = map[string]*testproto.Message{"a": {Anint32: 3}}
+			},
+			Want: `amessage:
+  anint32: 2
+astring_message_map:
+  a:
+    anint32: 3
+`,
+		},
+		{
+			Name: "clear",
+			In: `astring: hello
+anint32: 1
+`,
+			Edit: func(m *testproto.Message) {
+				m.Astring = ""
+			},
+			Want: "anint32: 1\n",
+		},
+		{
+			Name: "nested",
+			In: `amessage:
+    # Kept.
+    astring: a
+    anint32: 1
+`,
+			Edit: func(m *testproto.Message) {
+				m.Amessage.Anint32 = 2
+			},
+			Want: `amessage:
+    # Kept.
+    astring: a
+    anint32: 2
+`,
+		},
+		{
+			Name: "alias",
+			In:   "anold: a\n",
+			Edit: func(m *testproto.Message) {
+				m.Arenamed = "b"
+			},
+			Want: "anold: b\n",
+		},
+		{
+			Name: "list",
+			In: `arepeated_int32: [1, 2, 3]
+arepeated_string:
+    - a # First.
+    - b
+`,
+			Edit: func(m *testproto.Message) {
+				m.ArepeatedInt32 = m.ArepeatedInt32[:2]
+				m.ArepeatedString = append(m.ArepeatedString, "c")
+			},
+			Want: `arepeated_int32: [1, 2]
+arepeated_string:
+    - a # First.
+    - b
+    - c
+`,
+		},
+		{
+			Name: "map",
+			In: `astring_int32_map:
+    b: 2 # Bee.
+    a: 1
+`,
+			Edit: func(m *testproto.Message) {
+				m.AstringInt32Map["b"] = 3
+				delete(m.AstringInt32Map, "a")
+				m.AstringInt32Map["c"] = 4
+			},
+			Want: `astring_int32_map:
+    b: 3 # Bee.
+    c: 4
+`,
+		},
+		{
+			Name: "mergeKey",
+			In: `amessage: &base
+    anint32: 1
+astring_message_map:
+    a:
+        <<: *base
+        astring: a
+`,
+			Edit: func(m *testproto.Message) {
+				m.AstringMessageMap["a"].Anint32 = 2
+			},
+			Want: `amessage: &base
+    anint32: 1
+astring_message_map:
+    a:
+        !!merge <<: *base
+        astring: a
+        anint32: 2
+`,
+		},
+		{
+			Name: "alias node",
+			In: `amessage: &base
+    anint32: 1
+astring_message_map:
+    a: *base
+`,
+			Edit: func(m *testproto.Message) {
+				m.AstringMessageMap["a"].Anint32 = 2
+			},
+			Want: `amessage: &base
+    anint32: 1
+astring_message_map:
+    a:
+        anint32: 2
+`,
+		},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Name, func(t *testing.T) {
+			var m testproto.Message
+			doc, err := NewDecoder(strings.NewReader(tst.In)).DecodeDocument(&m)
+			if err != nil {
+				t.Fatalf("DecodeDocument failed: %v", err)
+			}
+
+			tst.Edit(&m)
+
+			var buf bytes.Buffer
+			e := NewEncoder(&buf)
+			if err := e.EncodeDocument(doc, &m); err != nil {
+				t.Fatalf("EncodeDocument failed: %v", err)
+			}
+			if err := e.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+
+			if diff := cmp.Diff(tst.Want, buf.String()); diff != "" {
+				t.Errorf("EncodeDocument: +got, -want:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDocumentIndent(t *testing.T) {
+	tsts := []struct {
+		Name string
+		In   string
+		Want int
+	}{
+		{"flat", "a: 1\n", 4},
+		{"mapping", "a:\n  b: 1\n", 2},
+		{"sequence", "a:\n   - 1\n", 3},
+		{"zeroSequence", "a:\n- 1\nb:\n  c: 1\n", 2},
+		{"flow", "a: {b: 1}\nc:\n    d: 1\n", 4},
+		{"inSequence", "- a:\n    b: 1\n", 2},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Name, func(t *testing.T) {
+			var n yaml.Node
+			if err := yaml.Unmarshal([]byte(tst.In), &n); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if got := DocumentIndent(&n); got != tst.Want {
+				t.Errorf("DocumentIndent: got %d, want %d", got, tst.Want)
+			}
+		})
+	}
+}
+
+func TestEncoderUpdateDocumentError(t *testing.T) {
+	tsts := []struct {
+		Name string
+		In   string
+		Edit func(*testproto.Message)
+		Want string
+	}{
+		{
+			Name: "anchor",
+			In: `amessage: &base
+    anint32: 1
+astring_message_map:
+    a: *base
+`,
+			Edit: func(m *testproto.Message) {
+				m.Amessage.Anint32 = 2
+			},
+			Want: "protoyaml: amessage: cannot change the value of anchor &base",
+		},
+		{
+			Name: "mergeKey",
+			In: `amessage: &base
+    anint32: 1
+astring_message_map:
+    a:
+        <<: *base
+`,
+			Edit: func(m *testproto.Message) {
+				m.AstringMessageMap["a"].Anint32 = 0
+			},
+			Want: `protoyaml: astring_message_map["a"].anint32: cannot clear a field set through a merge key`,
+		},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Name, func(t *testing.T) {
+			var m testproto.Message
+			doc, err := NewDecoder(strings.NewReader(tst.In)).DecodeDocument(&m)
+			if err != nil {
+				t.Fatalf("DecodeDocument failed: %v", err)
+			}
+
+			tst.Edit(&m)
+
+			err = NewEncoder(nil).UpdateDocument(doc, &m)
+			if err == nil || err.Error() != tst.Want {
+				t.Errorf("UpdateDocument: got %v, want %q", err, tst.Want)
+			}
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package protoyaml
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"gopkg.in/yaml.v3"
+)
+
+// Marshal returns the YAML representation of m.
+func Marshal(m protoreflect.ProtoMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	if err := e.Encode(m); err != nil {
+		return nil, err
+	}
+	if err := e.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// MarshalNode returns the YAML representation of m as a node tree,
+// e.g. to embed it in a larger YAML document.
+func MarshalNode(m protoreflect.ProtoMessage) (*yaml.Node, error) {
+	return NewEncoder(nil).EncodeNode(m)
+}
+
+// An Encoder can be used to encode Protobuf messages as one or more
+// YAML documents. It is not goroutine-safe, but is
+// goroutine-compatible.
+//
+// The output can be read back by a Decoder. Fields are written in
+// declaration order, and fields that are not set are left out.
+type Encoder struct {
+	ye       *yaml.Encoder
+	r        protoregistry.MessageTypeResolver
+	comments bool
+	started  bool
+}
+
+// NewEncoder creates a new encoder writing a stream of YAML text to w.
+// Close must be called to flush the stream. If w is nil, only
+// EncodeNode can be used.
+func NewEncoder(w io.Writer) *Encoder {
+	e := &Encoder{r: protoregistry.GlobalTypes}
+	if w != nil {
+		e.ye = yaml.NewEncoder(w)
+	}
+	return e
+}
+
+// MessageTypeResolver sets a custom resolver for anypb.Any types. The
+// default in NewEncoder is protoregistry.GlobalTypes.
+func (e *Encoder) MessageTypeResolver(r protoregistry.MessageTypeResolver) {
+	e.r = r
+}
+
+// Encode writes a message as the next document. The argument can
+// either be a proto.Message, or a protoreflect.Message.
+func (e *Encoder) Encode(v interface{}) error {
+	if e.ye == nil {
+		return fmt.Errorf("protoyaml: the encoder has no writer")
+	}
+	n, err := e.EncodeNode(v)
+	if err != nil {
+		return err
+	}
+	e.started = true
+	return e.ye.Encode(n)
+}
+
+// EncodeNode returns a message as a node tree, without writing it to
+// the stream. The argument can either be a proto.Message, or a
+// protoreflect.Message.
+func (e *Encoder) EncodeNode(v interface{}) (*yaml.Node, error) {
+	switch m := v.(type) {
+	case nil:
+		return nil, fmt.Errorf("protoyaml: nil source message")
+	case protoreflect.Message:
+		return e.encodeMessage(m)
+	case protoreflect.ProtoMessage:
+		return e.encodeMessage(m.ProtoReflect())
+	default:
+		return nil, fmt.Errorf("protoyaml: cannot marshal a %T", v)
+	}
+}
+
+// Close flushes the stream.
+func (e *Encoder) Close() error {
+	if e.ye == nil {
+		return nil
+	}
+	return e.ye.Close()
+}
+
+// encodeMessage encodes a Protobuf message as a mapping.
+func (e *Encoder) encodeMessage(m protoreflect.Message) (*yaml.Node, error) {
+	if n, ok, err := e.encodeKnownType(m); err != nil {
+		return nil, err
+	} else if ok {
+		return n, nil
+	}
+
+	out := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, fd := range populatedFields(m) {
+		n, err := e.encodeField(fd, m.Get(fd))
+		if err != nil {
+			return nil, err
+		}
+		kn := stringNode(fieldKey(fd))
+		if e.comments {
+			addFieldComments(kn, n, fd)
+		}
+		out.Content = append(out.Content, kn, n)
+	}
+	return out, nil
+}
+
+// populatedFields returns the fields that are set in m, in
+// declaration order, followed by extension fields in number order.
+// Fields with implicit presence are set if they are not the zero
+// value.
+func populatedFields(m protoreflect.Message) []protoreflect.FieldDescriptor {
+	var fields, exts []protoreflect.FieldDescriptor
+	fds := m.Descriptor().Fields()
+	for i := 0; i < fds.Len(); i++ {
+		if fd := fds.Get(i); m.Has(fd) {
+			fields = append(fields, fd)
+		}
+	}
+	m.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		if fd.IsExtension() {
+			exts = append(exts, fd)
+		}
+		return true
+	})
+	sort.Slice(exts, func(i, j int) bool { return exts[i].Number() < exts[j].Number() })
+	return append(fields, exts...)
+}
+
+// fieldKey returns the mapping key used for a field. This is the
+// inverse of Decoder.findField.
+func fieldKey(fd protoreflect.FieldDescriptor) string {
+	if fd.IsExtension() {
+		return "[" + string(fd.FullName()) + "]"
+	}
+	return string(fd.Name())
+}
+
+// encodeField encodes the value of a field, which may be a map or a
+// list.
+func (e *Encoder) encodeField(fd protoreflect.FieldDescriptor, v protoreflect.Value) (*yaml.Node, error) {
+	if fd.IsMap() {
+		out := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		mp := v.Map()
+		keys := make([]protoreflect.MapKey, 0, mp.Len())
+		mp.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+			keys = append(keys, k)
+			return true
+		})
+		sortMapKeys(keys)
+		for _, k := range keys {
+			kn, err := e.encodeValue(fd.MapKey(), k.Value())
+			if err != nil {
+				return nil, err
+			}
+			vn, err := e.encodeSingular(fd.MapValue(), mp.Get(k))
+			if err != nil {
+				return nil, err
+			}
+			out.Content = append(out.Content, kn, vn)
+		}
+		return out, nil
+	}
+
+	if fd.IsList() {
+		out := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		l := v.List()
+		for i := 0; i < l.Len(); i++ {
+			n, err := e.encodeSingular(fd, l.Get(i))
+			if err != nil {
+				return nil, err
+			}
+			out.Content = append(out.Content, n)
+		}
+		return out, nil
+	}
+
+	return e.encodeSingular(fd, v)
+}
+
+// encodeSingular encodes a message or a non-compound value.
+func (e *Encoder) encodeSingular(fd protoreflect.FieldDescriptor, v protoreflect.Value) (*yaml.Node, error) {
+	if isMessageKind(fd) {
+		return e.encodeMessage(v.Message())
+	}
+	return e.encodeValue(fd, v)
+}
+
+// encodeValue encodes a non-compound value, based on the kind of
+// field it is. This is the inverse of Decoder.decodeValue.
+func (e *Encoder) encodeValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) (*yaml.Node, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return scalarNode("!!bool", strconv.FormatBool(v.Bool())), nil
+
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return scalarNode("!!int", strconv.FormatInt(v.Int(), 10)), nil
+
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return scalarNode("!!int", strconv.FormatUint(v.Uint(), 10)), nil
+
+	case protoreflect.FloatKind:
+		return floatNode(v.Float(), 32), nil
+
+	case protoreflect.DoubleKind:
+		return floatNode(v.Float(), 64), nil
+
+	case protoreflect.StringKind:
+		return stringNode(v.String()), nil
+
+	case protoreflect.BytesKind:
+		return stringNode(base64.StdEncoding.EncodeToString(v.Bytes())), nil
+
+	case protoreflect.EnumKind:
+		if evd := fd.Enum().Values().ByNumber(v.Enum()); evd != nil {
+			return stringNode(string(evd.Name())), nil
+		}
+		return scalarNode("!!int", strconv.FormatInt(int64(v.Enum()), 10)), nil
+
+	default:
+		return nil, fmt.Errorf("protoyaml: cannot marshal a %v", fd.Kind())
+	}
+}
+
+// scalarNode returns a scalar node with the given tag.
+func scalarNode(tag, value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+}
+
+// stringNode returns a string scalar node. The YAML encoder quotes it
+// if it would otherwise be read back as another type.
+func stringNode(s string) *yaml.Node {
+	n := scalarNode("!!str", s)
+	if s == "<<" {
+		// The YAML encoder doesn't quote merge keys by itself.
+		n.Style = yaml.DoubleQuotedStyle
+	}
+	return n
+}
+
+// floatNode returns a float scalar node, using the YAML spelling of
+// infinities and NaN. Integral values get a fraction so they are not
+// read back as integers by plain YAML tools.
+func floatNode(f float64, bitSize int) *yaml.Node {
+	switch {
+	case math.IsInf(f, 1):
+		return scalarNode("!!float", ".inf")
+	case math.IsInf(f, -1):
+		return scalarNode("!!float", "-.inf")
+	case math.IsNaN(f):
+		return scalarNode("!!float", ".nan")
+	}
+
+	s := strconv.FormatFloat(f, 'g', -1, bitSize)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return scalarNode("!!float", s)
+}
+
+// sortMapKeys sorts map keys of the same kind in their natural order,
+// to make the output deterministic.
+func sortMapKeys(keys []protoreflect.MapKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		switch a := keys[i].Interface().(type) {
+		case bool:
+			return !a && keys[j].Bool()
+		case int32, int64:
+			return keys[i].Int() < keys[j].Int()
+		case uint32, uint64:
+			return keys[i].Uint() < keys[j].Uint()
+		default:
+			return keys[i].String() < keys[j].String()
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package protoyaml
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/testing/protocmp"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tommie/protoyaml-go/internal/testproto"
+)
+
+func ExampleMarshal() {
+	bs, err := Marshal(&testproto.Message{Astring: "hello", Anenum: testproto.Enum_ONE})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Print(string(bs))
+	// Output:
+	// astring: hello
+	// anenum: ONE
+}
+
+func TestMarshal(t *testing.T) {
+	got, err := Marshal(&testproto.Message{
+		Abool:             true,
+		Anint32:           -42,
+		Auint64:           math.MaxUint64,
+		Afloat:            42,
+		Adouble:           math.Inf(-1),
+		Abytes:            []byte{0, 0, 0},
+		Astring:           "true",
+		ArepeatedInt32:    []int32{1, 2},
+		ArepeatedNenum:    []testproto.Enum{testproto.Enum_ONE, 42},
+		AstringInt32Map:   map[string]int32{"b": 2, "a": 1},
+		AstringMessageMap: map[string]*testproto.Message{"a": {}},
+		Amessage:          &testproto.Message{Anint32: 43},
+	})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	want := `abool: true
+anint32: -42
+auint64: 18446744073709551615
+afloat: 42.0
+adouble: -.inf
+abytes: AAAA
+astring: "true"
+arepeated_int32:
+    - 1
+    - 2
+arepeated_nenum:
+    - ONE
+    - 42
+astring_int32_map:
+    a: 1
+    b: 2
+astring_message_map:
+    a: {}
+amessage:
+    anint32: 43
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("Marshal: +got, -want:\n%s", diff)
+	}
+}
+
+func TestMarshalNode(t *testing.T) {
+	got, err := MarshalNode(&testproto.Message{Astring: "hello"})
+	if err != nil {
+		t.Fatalf("MarshalNode failed: %v", err)
+	}
+
+	want := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: "astring"},
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: "hello"},
+	}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("MarshalNode: +got, -want:\n%s", diff)
+	}
+}
+
+func TestEncoderEncode(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	if err := e.Encode(&testproto.Message{Astring: "hello"}); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if err := e.Encode((&testproto.Message{Anint32: 42}).ProtoReflect()); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	want := `astring: hello
+---
+anint32: 42
+`
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("Encode: +got, -want:\n%s", diff)
+	}
+}
+
+func TestEncoderEncodeNoWriter(t *testing.T) {
+	e := NewEncoder(nil)
+	if err := e.Encode(&testproto.Message{}); err == nil {
+		t.Errorf("Encode: got nil error, want an error")
+	}
+	if err := e.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	tsts := []struct {
+		Name string
+		Msg  protoreflect.ProtoMessage
+	}{
+		{"scalars", &testproto.Message{Abool: true, Ansint32: -1, Afixed64: 2, Afloat: 0.5, Adouble: 1e100, Abytes: []byte("hello"), Astring: "null", Anenum: testproto.Enum_ONE}},
+		{"repeated", &testproto.Message{ArepeatedString: []string{"a", "1", ""}, ArepeatedDouble: []float64{1, math.NaN()}, ArepeatedMessage: []*testproto.Message{{}, {Anint32: 1}}}},
+		{"maps", &testproto.Message{AstringInt32Map: map[string]int32{"<<": 1, "a": 2}, AstringMessageMap: map[string]*testproto.Message{"a": {Astring: "b"}}}},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Name, func(t *testing.T) {
+			bs, err := Marshal(tst.Msg)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+
+			got := tst.Msg.ProtoReflect().Type().New().Interface()
+			if err := Unmarshal(bs, got); err != nil {
+				t.Fatalf("Unmarshal(%q) failed: %v", bs, err)
+			}
+
+			if diff := cmp.Diff(tst.Msg, got, protocmp.Transform(), cmp.Comparer(func(a, b float64) bool { return a == b || math.IsNaN(a) && math.IsNaN(b) })); diff != "" {
+				t.Errorf("Unmarshal(Marshal()): +got, -want:\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package protoyaml
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ExpandEnv enables expansion of environment variables in scalar
+// values, e.g. `password: ${DB_PASSWORD}` or `port: ${PORT:-8080}`.
+// Expansion happens before the value is converted, so numeric fields,
+// enums and durations can also be set this way. Use "$$" for a
+// literal dollar sign. Values produced by tag handlers, like secrets,
+// are not expanded. The lookup function is typically os.LookupEnv.
+// In strict mode, using an unset variable without a default is an
+// error. Otherwise it expands to the empty string.
+func (d *Decoder) ExpandEnv(lookup func(string) (string, bool), strict bool) {
+	d.lookupEnv = lookup
+	d.strictEnv = strict
+}
+
+// expandScalar returns n with environment variables expanded, if
+// enabled, and n was not produced by a tag handler. The returned node
+// has no tag, so the expanded text is resolved like a plain scalar.
+func (d *Decoder) expandScalar(n *yaml.Node) (*yaml.Node, error) {
+	if d.lookupEnv == nil || d.tagged[n] || n.Kind != yaml.ScalarNode || !strings.Contains(n.Value, "$") {
+		return n, nil
+	}
+
+	s, err := d.expandEnv(n.Value)
+	if err != nil {
+		return nil, d.nodeErrorf(n, "%v", err)
+	}
+	nn := *n
+	nn.Value = s
+	nn.Tag = ""
+	nn.Style = 0
+	return &nn, nil
+}
+
+// expandEnv replaces ${NAME} and ${NAME:-default} references in s.
+func (d *Decoder) expandEnv(s string) (string, error) {
+	var sb strings.Builder
+	for {
+		i := strings.IndexByte(s, '$')
+		if i < 0 || i+1 == len(s) {
+			sb.WriteString(s)
+			return sb.String(), nil
+		}
+		sb.WriteString(s[:i])
+		s = s[i+1:]
+
+		switch s[0] {
+		case '$':
+			sb.WriteByte('$')
+			s = s[1:]
+			continue
+		case '{':
+			// Handled below.
+		default:
+			sb.WriteByte('$')
+			continue
+		}
+
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated variable reference: ${%s", s[1:])
+		}
+		ref := s[1:end]
+		s = s[end+1:]
+
+		name, def, hasDefault := strings.Cut(ref, ":-")
+		if name == "" {
+			return "", fmt.Errorf("empty variable name in ${%s}", ref)
+		}
+		if v, ok := d.lookupEnv(name); ok && (v != "" || !hasDefault) {
+			sb.WriteString(v)
+		} else if hasDefault {
+			sb.WriteString(def)
+		} else if d.strictEnv {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package protoyaml
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/tommie/protoyaml-go/internal/testproto"
+)
+
+func TestDecoderExpandEnv(t *testing.T) {
+	env := map[string]string{"NAME": "world", "PORT": "8080", "EMPTY": "", "ENUM": "ONE", "TIMEOUT": "42s"}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	tsts := []struct {
+		Name string
+		YAML string
+		Want proto.Message
+	}{
+		{"string", `astring: hello ${NAME}`, &testproto.Message{Astring: "hello world"}},
+		{"int", `anint32: "${PORT}"`, &testproto.Message{Anint32: 8080}},
+		{"default", `anint32: ${MISSING:-42}`, &testproto.Message{Anint32: 42}},
+		{"emptyDefault", `astring: ${EMPTY:-x}`, &testproto.Message{Astring: "x"}},
+		{"empty", `astring: a${EMPTY}b`, &testproto.Message{Astring: "ab"}},
+		{"unsetNonStrict", `astring: a${MISSING}b`, &testproto.Message{Astring: "ab"}},
+		{"escape", `astring: $${NAME} $5`, &testproto.Message{Astring: "${NAME} $5"}},
+		{"enum", `anenum: ${ENUM}`, &testproto.Message{Anenum: testproto.Enum_ONE}},
+		{"repeated", `arepeated_string: [$NAME, "${NAME}"]`, &testproto.Message{ArepeatedString: []string{"$NAME", "world"}}},
+		{"mapKey", `astring_int32_map: {"${NAME}": 1}`, &testproto.Message{AstringInt32Map: map[string]int32{"world": 1}}},
+		{"duration", `aduration: ${TIMEOUT}`, &testproto.Known{Aduration: durationpb.New(42 * time.Second)}},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Name, func(t *testing.T) {
+			d := NewDecoder(strings.NewReader(tst.YAML))
+			d.ExpandEnv(lookup, false)
+
+			got := tst.Want.ProtoReflect().Type().New().Interface()
+			if err := d.Decode(got); err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+
+			if diff := cmp.Diff(tst.Want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("Decode: +got, -want:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecoderExpandEnvTag(t *testing.T) {
+	secrets := map[string]string{"a": "pa$$word", "b": "pa$${oops"}
+	d := NewDecoder(strings.NewReader("astring: !secret a\narepeated_string: [!secret b, \"$${NAME}\"]\n"))
+	d.ExpandEnv(func(string) (string, bool) { return "", false }, true)
+	d.RegisterTag("!secret", SecretTag(func(name string) (string, error) {
+		return secrets[name], nil
+	}))
+
+	var got testproto.Message
+	if err := d.Decode(&got); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	want := &testproto.Message{Astring: "pa$$word", ArepeatedString: []string{"pa$${oops", "${NAME}"}}
+	if diff := cmp.Diff(want, &got, protocmp.Transform()); diff != "" {
+		t.Errorf("Decode: +got, -want:\n%s", diff)
+	}
+}
+
+func TestDecoderExpandEnvError(t *testing.T) {
+	tsts := []struct {
+		Name string
+		YAML string
+		Want string
+	}{
+		{"strict", "astring: a\nanint32: ${PORT}", "protoyaml: config.yaml:2:10: environment variable PORT is not set"},
+		{"unterminated", "astring: ${NAME", "protoyaml: config.yaml:1:10: unterminated variable reference: ${NAME"},
+		{"emptyName", "astring: ${:-x}", "protoyaml: config.yaml:1:10: empty variable name in ${:-x}"},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Name, func(t *testing.T) {
+			d := NewDecoder(strings.NewReader(tst.YAML))
+			d.RecordSourceInfo("config.yaml")
+			d.ExpandEnv(func(string) (string, bool) { return "", false }, true)
+
+			var got testproto.Message
+			err := d.Decode(&got)
+			if err == nil || err.Error() != tst.Want {
+				t.Errorf("Decode: got %v, want %q", err, tst.Want)
+			}
+		})
+	}
+}
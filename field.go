@@ -0,0 +1,57 @@
+package protoyaml
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+// A Field holds a Protobuf message inside a struct that is encoded or
+// decoded by yaml.v3, e.g. a proto-typed section of a larger config
+// file:
+//
+//	type Config struct {
+//		Name   string                        `yaml:"name"`
+//		Server protoyaml.Field[*mypb.Server] `yaml:"server"`
+//	}
+//
+// The message is allocated when decoding, unless already set.
+type Field[T proto.Message] struct {
+	Message T
+}
+
+var (
+	_ yaml.Unmarshaler = (*Field[proto.Message])(nil)
+	_ yaml.Marshaler   = Field[proto.Message]{}
+	_ yaml.IsZeroer    = Field[proto.Message]{}
+)
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (f *Field[T]) UnmarshalYAML(n *yaml.Node) error {
+	if f.IsZero() {
+		var m proto.Message = f.Message
+		if m == nil {
+			return fmt.Errorf("protoyaml: cannot allocate a message for %T", f)
+		}
+		f.Message = m.ProtoReflect().Type().New().Interface().(T)
+	}
+	return UnmarshalNode(n, f.Message)
+}
+
+// MarshalYAML implements yaml.Marshaler. An unset field is encoded as
+// null.
+func (f Field[T]) MarshalYAML() (interface{}, error) {
+	if f.IsZero() {
+		return nil, nil
+	}
+	return MarshalNode(f.Message)
+}
+
+// IsZero implements yaml.IsZeroer, so an unset field is left out if
+// the struct field has the omitempty flag. A field is unset if Message
+// is nil.
+func (f Field[T]) IsZero() bool {
+	var m proto.Message = f.Message
+	return m == nil || !m.ProtoReflect().IsValid()
+}
@@ -0,0 +1,70 @@
+package protoyaml
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tommie/protoyaml-go/internal/testproto"
+)
+
+type fieldTestConfig struct {
+	Name     string                    `yaml:"name"`
+	Proto    Field[*testproto.Message] `yaml:"proto"`
+	Optional Field[*testproto.Message] `yaml:"optional,omitempty"`
+	List     []Field[*testproto.Known] `yaml:"list,omitempty"`
+}
+
+func TestFieldUnmarshalYAML(t *testing.T) {
+	var got fieldTestConfig
+	if err := yaml.Unmarshal([]byte(`name: hello
+proto: {astring: world, anenum: ONE}
+list: [{aduration: 1s}]`), &got); err != nil {
+		t.Fatalf("yaml.Unmarshal failed: %v", err)
+	}
+
+	want := fieldTestConfig{
+		Name:  "hello",
+		Proto: Field[*testproto.Message]{&testproto.Message{Astring: "world", Anenum: testproto.Enum_ONE}},
+		List:  []Field[*testproto.Known]{{&testproto.Known{Aduration: &durationpb.Duration{Seconds: 1}}}},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("yaml.Unmarshal: +got, -want:\n%s", diff)
+	}
+}
+
+func TestFieldUnmarshalYAMLError(t *testing.T) {
+	var got fieldTestConfig
+	if err := yaml.Unmarshal([]byte(`proto: {unknown: 42}`), &got); err == nil {
+		t.Errorf("yaml.Unmarshal: got nil error, want unknown field")
+	}
+
+	var anyMessage struct {
+		Proto Field[proto.Message] `yaml:"proto"`
+	}
+	if err := yaml.Unmarshal([]byte(`proto: {astring: world}`), &anyMessage); err == nil {
+		t.Errorf("yaml.Unmarshal: got nil error, want allocation error")
+	}
+}
+
+func TestFieldMarshalYAML(t *testing.T) {
+	got, err := yaml.Marshal(fieldTestConfig{
+		Name:  "hello",
+		Proto: Field[*testproto.Message]{&testproto.Message{Astring: "world"}},
+	})
+	if err != nil {
+		t.Fatalf("yaml.Marshal failed: %v", err)
+	}
+
+	want := `name: hello
+proto:
+    astring: world
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("yaml.Marshal: +got, -want:\n%s", diff)
+	}
+}
@@ -0,0 +1,330 @@
+package protoyaml
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"gopkg.in/yaml.v3"
+)
+
+// A Formatter rewrites YAML documents into a canonical form, guided by
+// a message descriptor, so style doesn't differ between files:
+//
+//   - Keys are in field declaration order, or number order, followed by
+//     extensions. Merge keys come first, and unknown keys last.
+//   - Field aliases are replaced by the current field names.
+//   - Map entries are sorted by key.
+//   - Enum numbers with a name are written as the name.
+//   - Durations are written like protojson, e.g. "90s", or as Go
+//     durations, e.g. "1m30s".
+//   - Strings are only quoted if needed.
+//
+// Nodes are reordered and updated in place, so comments follow the
+// value they belong to. Aliases, tagged values and $ref mappings are
+// kept as they are. The result decodes to the same message.
+type Formatter struct {
+	numberOrder bool
+	goDurations bool
+}
+
+// NewFormatter creates a formatter ordering keys in declaration order,
+// and writing durations like protojson.
+func NewFormatter() *Formatter {
+	return &Formatter{}
+}
+
+// FieldNumberOrder makes keys be ordered by field number, instead of
+// declaration order.
+func (f *Formatter) FieldNumberOrder(enable bool) {
+	f.numberOrder = enable
+}
+
+// GoDurations makes durations be written like Go durations, e.g.
+// "1m30s", instead of like protojson, e.g. "90s".
+func (f *Formatter) GoDurations(enable bool) {
+	f.goDurations = enable
+}
+
+// Format returns a stream of YAML documents in canonical form. Each
+// document is a md message. The indentation of the first document is
+// kept, see DocumentIndent. A stream holding only comments is returned
+// unchanged.
+func (f *Formatter) Format(bs []byte, md protoreflect.MessageDescriptor) ([]byte, error) {
+	var buf bytes.Buffer
+	ye := yaml.NewEncoder(&buf)
+	yd := yaml.NewDecoder(bytes.NewReader(bs))
+	started := false
+	for {
+		var doc yaml.Node
+		if err := yd.Decode(&doc); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		f.FormatNode(&doc, md)
+		if !started {
+			ye.SetIndent(DocumentIndent(&doc))
+			started = true
+		}
+		if err := ye.Encode(&doc); err != nil {
+			return nil, err
+		}
+	}
+	if !started {
+		// Only comments, or nothing. yaml.v3 cannot close a stream
+		// without documents.
+		return bs, nil
+	}
+	if err := ye.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// FormatNode updates a YAML document in place, into canonical form.
+// The node is a document or a mapping to be decoded as md.
+func (f *Formatter) FormatNode(n *yaml.Node, md protoreflect.MessageDescriptor) {
+	f.formatMessage(n, md, map[*yaml.Node]bool{})
+}
+
+// A formatPair is a key and value in a mapping being sorted.
+type formatPair struct {
+	k, v *yaml.Node
+
+	// group is 0 for merge keys, 1 for fields, 2 for extensions and 3
+	// for unknown keys. Fields are sorted by order, and extensions by
+	// name.
+	group int
+	order int
+}
+
+// formatMessage formats a message mapping. Nodes in visited are
+// skipped, so anchored nodes are only formatted once.
+func (f *Formatter) formatMessage(n *yaml.Node, md protoreflect.MessageDescriptor, visited map[*yaml.Node]bool) {
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Kind != yaml.MappingNode || visited[n] || isKnownType(md) || !isPlainTag(n) {
+		return
+	}
+	visited[n] = true
+
+	pairs := make([]formatPair, 0, len(n.Content)/2)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		p := formatPair{k: n.Content[i], v: n.Content[i+1], group: 3}
+		switch {
+		case isMergeKey(p.k):
+			p.group = 0
+			if p.v.Kind == yaml.SequenceNode {
+				for _, v := range p.v.Content {
+					f.formatMessage(v, md, visited)
+				}
+			} else {
+				f.formatMessage(p.v, md, visited)
+			}
+
+		case strings.HasPrefix(p.k.Value, "[") && strings.HasSuffix(p.k.Value, "]"):
+			p.group = 2
+
+		default:
+			fd := md.Fields().ByName(protoreflect.Name(p.k.Value))
+			if fd == nil {
+				fd = findAliasedField(md, p.k.Value)
+			}
+			if fd == nil {
+				break
+			}
+			p.group = 1
+			p.order = fd.Index()
+			if f.numberOrder {
+				p.order = int(fd.Number())
+			}
+			p.k.Value = string(fd.Name())
+			p.k.Style = 0
+			f.formatField(p.v, fd, visited)
+		}
+		pairs = append(pairs, p)
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		a, b := pairs[i], pairs[j]
+		switch {
+		case a.group != b.group:
+			return a.group < b.group
+		case a.group == 1:
+			return a.order < b.order
+		case a.group == 2:
+			return a.k.Value < b.k.Value
+		default:
+			return false
+		}
+	})
+	n.Content = n.Content[:0]
+	for _, p := range pairs {
+		n.Content = append(n.Content, p.k, p.v)
+	}
+}
+
+// formatField formats the value of a field, which may be a map or a
+// list.
+func (f *Formatter) formatField(n *yaml.Node, fd protoreflect.FieldDescriptor, visited map[*yaml.Node]bool) {
+	if n.Kind == yaml.AliasNode || !isPlainTag(n) {
+		return
+	}
+	switch {
+	case fd.IsMap():
+		if n.Kind == yaml.MappingNode {
+			f.formatMap(n, fd, visited)
+		}
+
+	case fd.IsList():
+		if n.Kind == yaml.SequenceNode {
+			for _, e := range n.Content {
+				f.formatValue(e, fd, visited)
+			}
+		}
+
+	default:
+		f.formatValue(n, fd, visited)
+	}
+}
+
+// formatMap sorts the entries of a map field by key, after any merge
+// keys, and formats the values.
+func (f *Formatter) formatMap(n *yaml.Node, fd protoreflect.FieldDescriptor, visited map[*yaml.Node]bool) {
+	type entry struct {
+		k, v *yaml.Node
+		key  protoreflect.MapKey
+	}
+	var merges, entries []entry
+	d := NewDecoder(nil)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		e := entry{k: n.Content[i], v: n.Content[i+1]}
+		if isMergeKey(e.k) {
+			merges = append(merges, e)
+			continue
+		}
+		kv, err := d.decodeValue(fd.MapKey(), e.k)
+		if err != nil {
+			// Leave invalid maps for the decoder to report.
+			return
+		}
+		e.key = kv.MapKey()
+		entries = append(entries, e)
+	}
+	for _, e := range entries {
+		if fd.MapKey().Kind() == protoreflect.StringKind {
+			formatString(e.k)
+		}
+		f.formatValue(e.v, fd.MapValue(), visited)
+	}
+
+	keys := make([]protoreflect.MapKey, len(entries))
+	for i, e := range entries {
+		keys[i] = e.key
+	}
+	sortMapKeys(keys)
+	byKey := make(map[interface{}][]entry, len(entries))
+	for _, e := range entries {
+		byKey[e.key.Interface()] = append(byKey[e.key.Interface()], e)
+	}
+
+	n.Content = n.Content[:0]
+	for _, e := range merges {
+		n.Content = append(n.Content, e.k, e.v)
+	}
+	for i, k := range keys {
+		if i > 0 && keys[i-1].Interface() == k.Interface() {
+			// Duplicates were all added the first time.
+			continue
+		}
+		for _, e := range byKey[k.Interface()] {
+			n.Content = append(n.Content, e.k, e.v)
+		}
+	}
+}
+
+// formatValue formats a singular value.
+func (f *Formatter) formatValue(n *yaml.Node, fd protoreflect.FieldDescriptor, visited map[*yaml.Node]bool) {
+	if n.Kind == yaml.AliasNode || !isPlainTag(n) {
+		return
+	}
+	if isMessageKind(fd) {
+		if fd.Message().FullName() == durationName && n.Kind == yaml.ScalarNode {
+			f.formatDuration(n)
+		} else {
+			f.formatMessage(n, fd.Message(), visited)
+		}
+		return
+	}
+	if n.Kind != yaml.ScalarNode {
+		return
+	}
+
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		if n.ShortTag() != "!!binary" {
+			formatString(n)
+		}
+
+	case protoreflect.BytesKind:
+		// Base64 is a plain string.
+
+	case protoreflect.EnumKind:
+		if n.ShortTag() == "!!int" {
+			var num int32
+			if err := n.Decode(&num); err != nil {
+				return
+			}
+			if evd := fd.Enum().Values().ByNumber(protoreflect.EnumNumber(num)); evd != nil {
+				n.Value = string(evd.Name())
+			}
+		}
+		if fd.Enum().Values().ByName(protoreflect.Name(n.Value)) != nil {
+			formatString(n)
+		}
+	}
+}
+
+// formatDuration rewrites a duration in the chosen form.
+func (f *Formatter) formatDuration(n *yaml.Node) {
+	dur, err := parseDuration(n.Value)
+	if err != nil {
+		return
+	}
+	gd := dur.AsDuration()
+	if rt := durationpb.New(gd); f.goDurations && rt.Seconds == dur.Seconds && rt.Nanos == dur.Nanos {
+		n.Value = gd.String()
+	} else {
+		dn, err := encodeJSONString(dur)
+		if err != nil {
+			return
+		}
+		n.Value = dn.Value
+	}
+	n.Tag = "!!str"
+	n.Style = 0
+}
+
+// formatString makes a scalar an unquoted string, which the YAML
+// encoder quotes if needed. Block styles are kept.
+func formatString(n *yaml.Node) {
+	n.Tag = "!!str"
+	if n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+		n.Style = 0
+	}
+}
+
+// isPlainTag returns true if a node has no custom tag, which would
+// give it a meaning the formatter doesn't know.
+func isPlainTag(n *yaml.Node) bool {
+	return n.Tag == "" || strings.HasPrefix(n.ShortTag(), "!!")
+}
@@ -0,0 +1,144 @@
+package protoyaml
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/tommie/protoyaml-go/internal/testproto"
+)
+
+func TestFormatterFormat(t *testing.T) {
+	tsts := []struct {
+		Name string
+		In   string
+		Opts func(*Formatter)
+		Want string
+	}{
+		{
+			Name: "order",
+			In: `# The message.
+amessage: {anint32: 1}
+astring: "hello" # Line.
+anenum: 1
+anold: "123"
+abool: true
+`,
+			Want: `abool: true
+astring: hello # Line.
+anenum: ONE
+arenamed: "123"
+# The message.
+amessage: {anint32: 1}
+`,
+		},
+		{
+			Name: "map",
+			In: `astring_int32_map:
+    b: 2
+    # A.
+    "a": 1
+`,
+			Want: `astring_int32_map:
+    # A.
+    a: 1
+    b: 2
+`,
+		},
+		{
+			Name: "kept",
+			In: `nosuch: x
+amessage: &m {astring: 'a'}
+arepeated_string: [!env HOME, 'b']
+`,
+			Want: `arepeated_string: [!env HOME, b]
+amessage: &m {astring: a}
+nosuch: x
+`,
+		},
+		{
+			Name: "indent",
+			In: `astring_int32_map:
+  b: 2
+  a: 1
+`,
+			Want: `astring_int32_map:
+  a: 1
+  b: 2
+`,
+		},
+		{
+			Name: "commentsOnly",
+			In:   "# Nothing yet.\n",
+			Want: "# Nothing yet.\n",
+		},
+		{
+			Name: "numberOrder",
+			In:   "arenamed: a\nanint32: 1\n",
+			Opts: func(f *Formatter) { f.FieldNumberOrder(true) },
+			Want: "anint32: 1\narenamed: a\n",
+		},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Name, func(t *testing.T) {
+			f := NewFormatter()
+			if tst.Opts != nil {
+				tst.Opts(f)
+			}
+			got, err := f.Format([]byte(tst.In), (&testproto.Message{}).ProtoReflect().Descriptor())
+			if err != nil {
+				t.Fatalf("Format failed: %v", err)
+			}
+			if diff := cmp.Diff(tst.Want, string(got)); diff != "" {
+				t.Errorf("Format: +got, -want:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFormatterFormatDuration(t *testing.T) {
+	md := (&testproto.Known{}).ProtoReflect().Descriptor()
+
+	got, err := NewFormatter().Format([]byte("aduration: 1m30s\n"), md)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if want := "aduration: 90s\n"; string(got) != want {
+		t.Errorf("Format: got %q, want %q", got, want)
+	}
+
+	f := NewFormatter()
+	f.GoDurations(true)
+	got, err = f.Format([]byte("aduration: 90.5s\n"), md)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if want := "aduration: 1m30.5s\n"; string(got) != want {
+		t.Errorf("Format: got %q, want %q", got, want)
+	}
+}
+
+func TestFormatterFormatRoundTrip(t *testing.T) {
+	in := `anold: 'x'
+astring_message_map:
+    b: {anenum: 2}
+    a:
+        amessage: {anint32: 3}
+anenum: 1
+`
+	var want, got testproto.Message
+	if err := Unmarshal([]byte(in), &want); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	bs, err := NewFormatter().Format([]byte(in), want.ProtoReflect().Descriptor())
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if err := Unmarshal(bs, &got); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if diff := cmp.Diff(&want, &got, protocmp.Transform()); diff != "" {
+		t.Errorf("Format: +got, -want:\n%s", diff)
+	}
+}
@@ -0,0 +1,268 @@
+package protoyaml
+
+import (
+	"math"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// JSONSchemaURI is the JSON Schema dialect produced by JSONSchema.
+const JSONSchemaURI = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema returns a JSON Schema describing the YAML documents a
+// Decoder accepts for md, e.g. for editors using the YAML language
+// server. Messages are defined in "$defs", by full name. Leading
+// comments of messages and fields become descriptions, if the
+// descriptors have source info. The result can be passed to
+// json.Marshal.
+//
+// Integers in other bases, like 0x1f, and .inf and .nan floats are
+// accepted as strings, without range checks. Messages with extension
+// ranges accept any "[full.name]" key, but the extension values are
+// not described. Merge keys and $ref mappings are not described. The
+// schema of a google.protobuf.Any only requires "@type".
+func JSONSchema(md protoreflect.MessageDescriptor) map[string]interface{} {
+	defs := map[string]interface{}{}
+	addMessageSchema(defs, md)
+	return map[string]interface{}{
+		"$schema": JSONSchemaURI,
+		"$ref":    messageSchemaRef(md),
+		"$defs":   defs,
+	}
+}
+
+// messageSchemaRef returns the reference to the definition of a
+// message.
+func messageSchemaRef(md protoreflect.MessageDescriptor) string {
+	return "#/$defs/" + string(md.FullName())
+}
+
+// addMessageSchema adds the definition of md, and the messages it
+// uses, to defs.
+func addMessageSchema(defs map[string]interface{}, md protoreflect.MessageDescriptor) {
+	name := string(md.FullName())
+	if _, ok := defs[name]; ok {
+		return
+	}
+
+	s := knownTypeSchema(md)
+	if s == nil {
+		// Set before recursing, for recursive messages.
+		s = map[string]interface{}{"type": "object"}
+		defs[name] = s
+
+		props := map[string]interface{}{}
+		var required []string
+		fds := md.Fields()
+		for i := 0; i < fds.Len(); i++ {
+			fd := fds.Get(i)
+			fs := fieldSchema(defs, fd)
+			props[string(fd.Name())] = fs
+			for _, alias := range fieldAliases(fd) {
+				as := copySchema(fs)
+				as["deprecated"] = true
+				props[alias] = as
+			}
+			if fd.Cardinality() == protoreflect.Required {
+				required = append(required, string(fd.Name()))
+			}
+		}
+		s["properties"] = props
+		if md.ExtensionRanges().Len() > 0 {
+			s["patternProperties"] = map[string]interface{}{`^\[.+\]$`: map[string]interface{}{}}
+		}
+		s["additionalProperties"] = false
+		if required != nil {
+			s["required"] = required
+		}
+	}
+	if desc := schemaDescription(md); desc != "" {
+		s["description"] = desc
+	}
+	defs[name] = s
+}
+
+// knownTypeSchema returns the schema of a well-known type with a
+// special YAML representation, or nil.
+func knownTypeSchema(md protoreflect.MessageDescriptor) map[string]interface{} {
+	switch md.FullName() {
+	case anyName:
+		return map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"@type": map[string]interface{}{"type": "string"}},
+			"required":   []string{"@type"},
+		}
+	case durationName:
+		return map[string]interface{}{
+			"type":    "string",
+			"pattern": `^-?([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`,
+		}
+	case fieldMaskName:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": map[string]interface{}{"type": "string"},
+		}
+	case timestampName:
+		return map[string]interface{}{
+			"type":   "string",
+			"format": "date-time",
+		}
+	default:
+		return nil
+	}
+}
+
+// fieldSchema returns the schema of a field value.
+func fieldSchema(defs map[string]interface{}, fd protoreflect.FieldDescriptor) map[string]interface{} {
+	var s map[string]interface{}
+	switch {
+	case fd.IsMap():
+		s = map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": valueSchema(defs, fd.MapValue()),
+		}
+		if ks := mapKeySchema(fd.MapKey()); ks != nil {
+			s["propertyNames"] = ks
+		}
+	case fd.IsList():
+		s = map[string]interface{}{
+			"type":  "array",
+			"items": valueSchema(defs, fd),
+		}
+	default:
+		s = valueSchema(defs, fd)
+	}
+
+	if desc := schemaDescription(fd); desc != "" {
+		s = copySchema(s)
+		s["description"] = desc
+	}
+	if isDeprecatedField(fd) {
+		s = copySchema(s)
+		s["deprecated"] = true
+	}
+	return s
+}
+
+// valueSchema returns the schema of a singular value of a field.
+func valueSchema(defs map[string]interface{}, fd protoreflect.FieldDescriptor) map[string]interface{} {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]interface{}{"type": "boolean"}
+
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return intSchema(map[string]interface{}{"type": "integer", "minimum": math.MinInt32, "maximum": math.MaxInt32}, true)
+
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return intSchema(map[string]interface{}{"type": "integer", "minimum": 0, "maximum": math.MaxUint32}, false)
+
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return intSchema(map[string]interface{}{"type": "integer"}, true)
+
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return intSchema(map[string]interface{}{"type": "integer", "minimum": 0}, false)
+
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		// YAML spells infinities and NaN .inf and .nan, which JSON
+		// Schema tools see as strings.
+		return map[string]interface{}{
+			"anyOf": []interface{}{
+				map[string]interface{}{"type": "number"},
+				map[string]interface{}{"type": "string", "pattern": `^([-+]?\.(inf|Inf|INF)|\.(nan|NaN|NAN))$`},
+			},
+		}
+
+	case protoreflect.StringKind:
+		// Any scalar is taken as its text.
+		return map[string]interface{}{"type": []string{"string", "number", "boolean"}}
+
+	case protoreflect.BytesKind:
+		return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+
+	case protoreflect.EnumKind:
+		return enumSchema(fd.Enum())
+
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		addMessageSchema(defs, fd.Message())
+		return map[string]interface{}{"$ref": messageSchemaRef(fd.Message())}
+
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// intSchema returns the schema of an integer, which can also be
+// written in another base, or with underscores, like 0x1f, 0o17, 0b1
+// and 1_000. JSON Schema tools see those as strings.
+func intSchema(s map[string]interface{}, signed bool) map[string]interface{} {
+	sign := `\+?`
+	if signed {
+		sign = `[-+]?`
+	}
+	return map[string]interface{}{
+		"anyOf": []interface{}{
+			s,
+			map[string]interface{}{"type": "string", "pattern": "^" + sign + `(0b[01_]+|0o?[0-7_]+|0x[0-9a-fA-F_]+|[0-9][0-9_]*)$`},
+		},
+	}
+}
+
+// enumSchema returns the schema of an enum value, which is a name, or
+// a number. Closed enums only accept declared numbers.
+func enumSchema(ed protoreflect.EnumDescriptor) map[string]interface{} {
+	var names []interface{}
+	var numbers []interface{}
+	evds := ed.Values()
+	for i := 0; i < evds.Len(); i++ {
+		names = append(names, string(evds.Get(i).Name()))
+		numbers = append(numbers, int32(evds.Get(i).Number()))
+	}
+
+	s := map[string]interface{}{}
+	if ed.IsClosed() {
+		s["enum"] = append(names, numbers...)
+	} else {
+		s["anyOf"] = []interface{}{
+			map[string]interface{}{"enum": names},
+			map[string]interface{}{"type": "integer", "minimum": math.MinInt32, "maximum": math.MaxInt32},
+		}
+	}
+	if desc := schemaDescription(ed); desc != "" {
+		s["description"] = desc
+	}
+	return s
+}
+
+// mapKeySchema returns the schema of map keys, as property names, or
+// nil if any string is accepted.
+func mapKeySchema(fd protoreflect.FieldDescriptor) map[string]interface{} {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]interface{}{"enum": []string{"true", "false"}}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return map[string]interface{}{"pattern": "^-?[0-9]+$"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return map[string]interface{}{"pattern": "^[0-9]+$"}
+	default:
+		return nil
+	}
+}
+
+// schemaDescription returns the leading comments of a descriptor, or
+// an empty string.
+func schemaDescription(d protoreflect.Descriptor) string {
+	loc := d.ParentFile().SourceLocations().ByDescriptor(d)
+	return strings.TrimSpace(loc.LeadingComments)
+}
+
+// copySchema returns a shallow copy of a schema.
+func copySchema(s map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(s)+1)
+	for k, v := range s {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,171 @@
+package protoyaml
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/tommie/protoyaml-go/internal/testproto"
+)
+
+// compileProto compiles a .proto file with source info, and returns
+// the named message.
+func compileProto(t *testing.T, src string, name protoreflect.FullName) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	c := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(map[string]string{"test.proto": src}),
+		}),
+		SourceInfoMode: protocompile.SourceInfoStandard,
+	}
+	files, err := c.Compile(context.Background(), "test.proto")
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	md := files[0].Messages().ByName(name.Name())
+	if md == nil {
+		t.Fatalf("no message %s", name)
+	}
+	return md
+}
+
+func TestJSONSchema(t *testing.T) {
+	md := compileProto(t, `syntax = "proto2";
+package conf;
+import "google/protobuf/duration.proto";
+
+// A server.
+message Server {
+  // Where to listen.
+  required string listen = 1;
+  optional google.protobuf.Duration timeout = 2;
+  repeated Server backends = 3;
+  map<uint32, Level> levels = 4;
+  optional bytes key = 5 [deprecated = true];
+  optional uint32 port = 6;
+  optional double ratio = 7;
+  extensions 100 to 200;
+}
+
+enum Level {
+  LOW = 0;
+  HIGH = 1;
+}
+`, "conf.Server")
+
+	bs, err := json.MarshalIndent(JSONSchema(md), "", "  ")
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	want := `{
+  "$defs": {
+    "conf.Server": {
+      "additionalProperties": false,
+      "description": "A server.",
+      "patternProperties": {
+        "^\\[.+\\]$": {}
+      },
+      "properties": {
+        "backends": {
+          "items": {
+            "$ref": "#/$defs/conf.Server"
+          },
+          "type": "array"
+        },
+        "key": {
+          "contentEncoding": "base64",
+          "deprecated": true,
+          "type": "string"
+        },
+        "levels": {
+          "additionalProperties": {
+            "enum": [
+              "LOW",
+              "HIGH",
+              0,
+              1
+            ]
+          },
+          "propertyNames": {
+            "pattern": "^[0-9]+$"
+          },
+          "type": "object"
+        },
+        "listen": {
+          "description": "Where to listen.",
+          "type": [
+            "string",
+            "number",
+            "boolean"
+          ]
+        },
+        "port": {
+          "anyOf": [
+            {
+              "maximum": 4294967295,
+              "minimum": 0,
+              "type": "integer"
+            },
+            {
+              "pattern": "^\\+?(0b[01_]+|0o?[0-7_]+|0x[0-9a-fA-F_]+|[0-9][0-9_]*)$",
+              "type": "string"
+            }
+          ]
+        },
+        "ratio": {
+          "anyOf": [
+            {
+              "type": "number"
+            },
+            {
+              "pattern": "^([-+]?\\.(inf|Inf|INF)|\\.(nan|NaN|NAN))$",
+              "type": "string"
+            }
+          ]
+        },
+        "timeout": {
+          "$ref": "#/$defs/google.protobuf.Duration"
+        }
+      },
+      "required": [
+        "listen"
+      ],
+      "type": "object"
+    },
+    "google.protobuf.Duration": {
+      "pattern": "^-?([0-9]+(\\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$",
+      "type": "string"
+    }
+  },
+  "$ref": "#/$defs/conf.Server",
+  "$schema": "https://json-schema.org/draft/2020-12/schema"
+}`
+	if diff := cmp.Diff(want, string(bs)); diff != "" {
+		t.Errorf("JSONSchema: +got, -want:\n%s", diff)
+	}
+}
+
+func TestJSONSchemaGenerated(t *testing.T) {
+	s := JSONSchema((&testproto.Message{}).ProtoReflect().Descriptor())
+
+	defs := s["$defs"].(map[string]interface{})
+	props := defs["protoyaml.test.Message"].(map[string]interface{})["properties"].(map[string]interface{})
+	if got := props["anold"].(map[string]interface{})["deprecated"]; got != true {
+		t.Errorf("JSONSchema anold: got deprecated %v, want true", got)
+	}
+	want := map[string]interface{}{
+		"anyOf": []interface{}{
+			map[string]interface{}{"enum": []interface{}{"ZERO", "ONE", "TWO"}},
+			map[string]interface{}{"type": "integer", "minimum": -2147483648, "maximum": 2147483647},
+		},
+	}
+	if diff := cmp.Diff(want, props["anenum"]); diff != "" {
+		t.Errorf("JSONSchema anenum: +got, -want:\n%s", diff)
+	}
+}
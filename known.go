@@ -3,8 +3,10 @@ package protoyaml
 import (
 	"fmt"
 	"strconv"
+	"time"
 
 	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/known/anypb"
 	"google.golang.org/protobuf/types/known/durationpb"
@@ -13,38 +15,49 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// decodeKnownType decodes well-known types that have a special YAML
+// representation. Types are matched by name, so dynamic messages
+// created from a descriptor set are handled like the generated ones.
 func (d *Decoder) decodeKnownType(out protoreflect.Message, v *yaml.Node) (bool, error) {
-	switch out.Type() {
-	case anyType:
+	switch out.Descriptor().FullName() {
+	case anyName:
 		return true, d.decodeAny(out, v)
-	case durationType:
+	case durationName:
 		return true, d.decodeDuration(out, v)
-	case fieldMaskType:
+	case fieldMaskName:
 		return true, d.decodeFieldMask(out, v)
-	case timestampType:
+	case timestampName:
 		return true, d.decodeTimestamp(out, v)
 	default:
 		return false, nil
 	}
+}
 
-	return true, nil
+// isKnownType returns true if the message has a special YAML
+// representation.
+func isKnownType(md protoreflect.MessageDescriptor) bool {
+	switch md.FullName() {
+	case anyName, durationName, fieldMaskName, timestampName:
+		return true
+	default:
+		return false
+	}
 }
 
 var (
-	anyType       = (&anypb.Any{}).ProtoReflect().Type()
-	durationType  = (&durationpb.Duration{}).ProtoReflect().Type()
-	fieldMaskType = (&fieldmaskpb.FieldMask{}).ProtoReflect().Type()
-	timestampType = (&timestamppb.Timestamp{}).ProtoReflect().Type()
+	anyName       = (&anypb.Any{}).ProtoReflect().Descriptor().FullName()
+	durationName  = (&durationpb.Duration{}).ProtoReflect().Descriptor().FullName()
+	fieldMaskName = (&fieldmaskpb.FieldMask{}).ProtoReflect().Descriptor().FullName()
+	timestampName = (&timestamppb.Timestamp{}).ProtoReflect().Descriptor().FullName()
 )
 
 func (d *Decoder) decodeAny(out protoreflect.Message, v *yaml.Node) error {
-	any := out.Interface().(*anypb.Any)
-
 	if v.Kind != yaml.MappingNode {
 		return fmt.Errorf("protoyaml: attempting to unmarshal a %v into an anypb.Any", v.Kind)
 	}
 
 	var mt protoreflect.MessageType
+	var typeURL string
 	var typeIndex int
 	var key string
 	for i, n := range v.Content {
@@ -59,7 +72,7 @@ func (d *Decoder) decodeAny(out protoreflect.Message, v *yaml.Node) error {
 			if err != nil {
 				return err
 			}
-			any.TypeUrl = n.Value
+			typeURL = n.Value
 			typeIndex = i - 1
 		}
 		key = ""
@@ -69,25 +82,50 @@ func (d *Decoder) decodeAny(out protoreflect.Message, v *yaml.Node) error {
 		return fmt.Errorf("protoyaml: no @type key in Any mapping")
 	}
 
-	n := *v
-	n.Content = append(append([]*yaml.Node{}, v.Content[:typeIndex]...), v.Content[typeIndex+2:]...)
-
 	m := mt.New()
-	if err := d.decodeMessage(m, &n, false); err != nil {
+	if err := d.decodeMessage(m, withoutKey(v, typeIndex), false); err != nil {
 		return err
 	}
 
-	return any.MarshalFrom(m.Interface())
+	bs, err := proto.Marshal(m.Interface())
+	if err != nil {
+		return err
+	}
+	fds := out.Descriptor().Fields()
+	out.Set(fds.ByName("type_url"), protoreflect.ValueOfString(typeURL))
+	out.Set(fds.ByName("value"), protoreflect.ValueOfBytes(bs))
+	return nil
 }
 
 func (d *Decoder) decodeDuration(out protoreflect.Message, v *yaml.Node) error {
-	dur := out.Interface().(*durationpb.Duration)
+	v, err := d.expandScalar(v)
+	if err != nil {
+		return err
+	}
 
 	if v.Kind != yaml.ScalarNode {
 		return fmt.Errorf("protoyaml: attempting to unmarshal a %v into a durationpb.Duration", v.Kind)
 	}
 
-	return protojson.Unmarshal([]byte(strconv.Quote(v.Value)), dur)
+	dur, err := parseDuration(v.Value)
+	if err != nil {
+		return err
+	}
+	return setSecondsNanos(out, dur.Seconds, dur.Nanos)
+}
+
+// parseDuration parses a duration like protojson does, e.g. "1.5s",
+// or a Go duration, like "1m30s".
+func parseDuration(s string) (*durationpb.Duration, error) {
+	var dur durationpb.Duration
+	if err := protojson.Unmarshal([]byte(strconv.Quote(s)), &dur); err != nil {
+		gd, gerr := time.ParseDuration(s)
+		if gerr != nil {
+			return nil, err
+		}
+		return durationpb.New(gd), nil
+	}
+	return &dur, nil
 }
 
 func (d *Decoder) decodeFieldMask(out protoreflect.Message, v *yaml.Node) error {
@@ -99,11 +137,107 @@ func (d *Decoder) decodeFieldMask(out protoreflect.Message, v *yaml.Node) error
 }
 
 func (d *Decoder) decodeTimestamp(out protoreflect.Message, v *yaml.Node) error {
-	dur := out.Interface().(*timestamppb.Timestamp)
+	v, err := d.expandScalar(v)
+	if err != nil {
+		return err
+	}
 
 	if v.Kind != yaml.ScalarNode {
 		return fmt.Errorf("protoyaml: attempting to unmarshal a %v into a timestamppb.Timestamp", v.Kind)
 	}
 
-	return protojson.Unmarshal([]byte(strconv.Quote(v.Value)), dur)
+	var ts timestamppb.Timestamp
+	if err := protojson.Unmarshal([]byte(strconv.Quote(v.Value)), &ts); err != nil {
+		return err
+	}
+	return setSecondsNanos(out, ts.Seconds, ts.Nanos)
+}
+
+// setSecondsNanos populates a Duration or Timestamp message, which may
+// be either generated or dynamic.
+func setSecondsNanos(out protoreflect.Message, secs int64, nanos int32) error {
+	fds := out.Descriptor().Fields()
+	out.Set(fds.ByName("seconds"), protoreflect.ValueOfInt64(secs))
+	out.Set(fds.ByName("nanos"), protoreflect.ValueOfInt32(nanos))
+	return nil
+}
+
+// encodeKnownType encodes well-known types that have a special YAML
+// representation. This is the inverse of decodeKnownType.
+func (e *Encoder) encodeKnownType(m protoreflect.Message) (*yaml.Node, bool, error) {
+	var n *yaml.Node
+	var err error
+	switch m.Descriptor().FullName() {
+	case anyName:
+		n, err = e.encodeAny(m)
+	case durationName:
+		n, err = e.encodeDuration(m)
+	case fieldMaskName:
+		n, err = e.encodeFieldMask(m)
+	case timestampName:
+		n, err = e.encodeTimestamp(m)
+	default:
+		return nil, false, nil
+	}
+	return n, true, err
+}
+
+func (e *Encoder) encodeAny(m protoreflect.Message) (*yaml.Node, error) {
+	fds := m.Descriptor().Fields()
+	typeURL := m.Get(fds.ByName("type_url")).String()
+	mt, err := e.r.FindMessageByURL(typeURL)
+	if err != nil {
+		return nil, err
+	}
+
+	mv := mt.New()
+	if err := proto.Unmarshal(m.Get(fds.ByName("value")).Bytes(), mv.Interface()); err != nil {
+		return nil, err
+	}
+	n, err := e.encodeMessage(mv)
+	if err != nil {
+		return nil, err
+	}
+	if n.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("protoyaml: cannot marshal a %s in an anypb.Any", mt.Descriptor().FullName())
+	}
+
+	n.Content = append([]*yaml.Node{stringNode("@type"), stringNode(typeURL)}, n.Content...)
+	return n, nil
+}
+
+func (e *Encoder) encodeDuration(m protoreflect.Message) (*yaml.Node, error) {
+	secs, nanos := getSecondsNanos(m)
+	return encodeJSONString(&durationpb.Duration{Seconds: secs, Nanos: nanos})
+}
+
+func (e *Encoder) encodeFieldMask(m protoreflect.Message) (*yaml.Node, error) {
+	fd := m.Descriptor().Fields().ByName("paths")
+	return e.encodeField(fd, m.Get(fd))
+}
+
+func (e *Encoder) encodeTimestamp(m protoreflect.Message) (*yaml.Node, error) {
+	secs, nanos := getSecondsNanos(m)
+	return encodeJSONString(&timestamppb.Timestamp{Seconds: secs, Nanos: nanos})
+}
+
+// getSecondsNanos reads a Duration or Timestamp message, which may be
+// either generated or dynamic.
+func getSecondsNanos(m protoreflect.Message) (int64, int32) {
+	fds := m.Descriptor().Fields()
+	return m.Get(fds.ByName("seconds")).Int(), int32(m.Get(fds.ByName("nanos")).Int())
+}
+
+// encodeJSONString returns the protojson representation of a message
+// that is represented as a JSON string.
+func encodeJSONString(m proto.Message) (*yaml.Node, error) {
+	bs, err := protojson.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	s, err := strconv.Unquote(string(bs))
+	if err != nil {
+		return nil, err
+	}
+	return stringNode(s), nil
 }
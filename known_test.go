@@ -10,6 +10,7 @@ import (
 	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/fieldmaskpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
+	"gopkg.in/yaml.v3"
 
 	"github.com/tommie/protoyaml-go/internal/testproto"
 )
@@ -28,24 +29,37 @@ func TestDecoderDecodeAny(t *testing.T) {
 	if err != nil {
 		t.Fatalf("anypb.New failed: %v", err)
 	}
-	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+	if diff := cmp.Diff(want, &got, protocmp.Transform()); diff != "" {
 		t.Errorf("decodeAny: +got, -want:\n%s", diff)
 	}
 }
 
 func TestDecoderDecodeDuration(t *testing.T) {
-	d, n, err := parseYAML(`"42s"`)
-	if err != nil {
-		t.Fatalf("parseYAML failed: %v", err)
-	}
-	var got durationpb.Duration
-	if err := d.decodeDuration(got.ProtoReflect(), n); err != nil {
-		t.Fatalf("decodeDuration failed: %v", err)
+	tsts := []struct {
+		In   string
+		Want time.Duration
+	}{
+		{`"42s"`, 42 * time.Second},
+		{`1.5s`, 1500 * time.Millisecond},
+		{`1m30s`, 90 * time.Second},
+		{`-2h`, -2 * time.Hour},
 	}
+	for _, tst := range tsts {
+		t.Run(tst.In, func(t *testing.T) {
+			d, n, err := parseYAML(tst.In)
+			if err != nil {
+				t.Fatalf("parseYAML failed: %v", err)
+			}
+			var got durationpb.Duration
+			if err := d.decodeDuration(got.ProtoReflect(), n); err != nil {
+				t.Fatalf("decodeDuration failed: %v", err)
+			}
 
-	want := durationpb.New(42 * time.Second)
-	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
-		t.Errorf("decodeDuration: +got, -want:\n%s", diff)
+			want := durationpb.New(tst.Want)
+			if diff := cmp.Diff(want, &got, protocmp.Transform()); diff != "" {
+				t.Errorf("decodeDuration: +got, -want:\n%s", diff)
+			}
+		})
 	}
 }
 
@@ -63,7 +77,7 @@ func TestDecoderDecodeFieldMask(t *testing.T) {
 	if err != nil {
 		t.Fatalf("fieldmaskpb.New failed: %v", err)
 	}
-	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+	if diff := cmp.Diff(want, &got, protocmp.Transform()); diff != "" {
 		t.Errorf("decodeFieldMask: +got, -want:\n%s", diff)
 	}
 }
@@ -79,7 +93,85 @@ func TestDecoderDecodeTimestamp(t *testing.T) {
 	}
 
 	want := timestamppb.New(time.Date(2006, 1, 2, 15, 4, 5, 999000000, time.UTC))
-	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+	if diff := cmp.Diff(want, &got, protocmp.Transform()); diff != "" {
 		t.Errorf("decodeTimestamp: +got, -want:\n%s", diff)
 	}
 }
+
+func TestEncoderEncodeAny(t *testing.T) {
+	m, err := anypb.New(&testproto.Message{Astring: "hello"})
+	if err != nil {
+		t.Fatalf("anypb.New failed: %v", err)
+	}
+	got, err := NewEncoder(nil).encodeAny(m.ProtoReflect())
+	if err != nil {
+		t.Fatalf("encodeAny failed: %v", err)
+	}
+
+	want := "{'@type': type.googleapis.com/protoyaml.test.Message, astring: hello}\n"
+	if diff := cmp.Diff(want, flowString(t, got)); diff != "" {
+		t.Errorf("encodeAny: +got, -want:\n%s", diff)
+	}
+}
+
+func TestEncoderEncodeDuration(t *testing.T) {
+	got, err := NewEncoder(nil).encodeDuration(durationpb.New(42 * time.Second).ProtoReflect())
+	if err != nil {
+		t.Fatalf("encodeDuration failed: %v", err)
+	}
+
+	want := "42s\n"
+	if diff := cmp.Diff(want, flowString(t, got)); diff != "" {
+		t.Errorf("encodeDuration: +got, -want:\n%s", diff)
+	}
+}
+
+func TestEncoderEncodeFieldMask(t *testing.T) {
+	m, err := fieldmaskpb.New(&testproto.Message{}, "amessage.anint32", "astring")
+	if err != nil {
+		t.Fatalf("fieldmaskpb.New failed: %v", err)
+	}
+	got, err := NewEncoder(nil).encodeFieldMask(m.ProtoReflect())
+	if err != nil {
+		t.Fatalf("encodeFieldMask failed: %v", err)
+	}
+
+	want := "[amessage.anint32, astring]\n"
+	if diff := cmp.Diff(want, flowString(t, got)); diff != "" {
+		t.Errorf("encodeFieldMask: +got, -want:\n%s", diff)
+	}
+}
+
+func TestEncoderEncodeTimestamp(t *testing.T) {
+	got, err := NewEncoder(nil).encodeTimestamp(timestamppb.New(time.Date(2006, 1, 2, 15, 4, 5, 999000000, time.UTC)).ProtoReflect())
+	if err != nil {
+		t.Fatalf("encodeTimestamp failed: %v", err)
+	}
+
+	want := "\"2006-01-02T15:04:05.999Z\"\n"
+	if diff := cmp.Diff(want, flowString(t, got)); diff != "" {
+		t.Errorf("encodeTimestamp: +got, -want:\n%s", diff)
+	}
+}
+
+// flowString renders a node in flow style, for compact comparisons.
+func flowString(t *testing.T, n *yaml.Node) string {
+	t.Helper()
+
+	var setStyle func(n *yaml.Node)
+	setStyle = func(n *yaml.Node) {
+		if n.Kind != yaml.ScalarNode {
+			n.Style |= yaml.FlowStyle
+		}
+		for _, c := range n.Content {
+			setStyle(c)
+		}
+	}
+	setStyle(n)
+
+	bs, err := yaml.Marshal(n)
+	if err != nil {
+		t.Fatalf("yaml.Marshal failed: %v", err)
+	}
+	return string(bs)
+}
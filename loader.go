@@ -0,0 +1,112 @@
+package protoyaml
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// A Loader assembles one message from layered YAML files, e.g.
+// base.yaml, region.yaml and host.yaml. Each document of each layer is
+// applied in order as a patch (see Decoder.Patch), so later layers
+// override scalar fields, and message fields are merged. By default,
+// repeated and map fields are replaced as a whole.
+type Loader struct {
+	layers    []loaderLayer
+	configure func(*Decoder)
+}
+
+// A loaderLayer is a named source of YAML documents.
+type loaderLayer struct {
+	name string
+	open func() (io.ReadCloser, error)
+}
+
+// NewLoader creates a new loader with no layers.
+func NewLoader() *Loader {
+	return &Loader{}
+}
+
+// Configure sets a function called on the decoder of each layer, e.g.
+// to select another patch strategy, or to add patch keys.
+func (l *Loader) Configure(f func(*Decoder)) {
+	l.configure = f
+}
+
+// AddReader adds a layer read from r. The name is used in errors and
+// source positions.
+func (l *Loader) AddReader(name string, r io.Reader) {
+	l.layers = append(l.layers, loaderLayer{
+		name: name,
+		open: func() (io.ReadCloser, error) { return io.NopCloser(r), nil },
+	})
+}
+
+// AddFile adds a layer read from a file. The file is opened by Load.
+func (l *Loader) AddFile(fsys fs.FS, name string) {
+	l.layers = append(l.layers, loaderLayer{
+		name: name,
+		open: func() (io.ReadCloser, error) { return fsys.Open(name) },
+	})
+}
+
+// AddGlob adds a layer for each file matching the pattern, in lexical
+// order. See fs.Glob for the pattern syntax.
+func (l *Loader) AddGlob(fsys fs.FS, pattern string) error {
+	names, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		l.AddFile(fsys, name)
+	}
+	return nil
+}
+
+// Load decodes all layers onto m. The returned SourceInfo tells which
+// layer supplied the final value of each field: the file name of a
+// position is the name of the layer. Required fields must be set
+// once all layers have been applied.
+func (l *Loader) Load(m proto.Message) (*SourceInfo, error) {
+	si := newSourceInfo(m.ProtoReflect().Descriptor())
+	for _, layer := range l.layers {
+		if err := l.loadLayer(m, layer, si); err != nil {
+			return nil, fmt.Errorf("%s: %w", layer.name, err)
+		}
+	}
+	if err := proto.CheckInitialized(m); err != nil {
+		return nil, err
+	}
+	return si, nil
+}
+
+// loadLayer applies all documents of a layer onto m.
+func (l *Loader) loadLayer(m proto.Message, layer loaderLayer, si *SourceInfo) error {
+	r, err := layer.open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	d := NewDecoder(r)
+	d.Patch(PatchReplace)
+	if l.configure != nil {
+		l.configure(d)
+	}
+	d.AllowPartial(true)
+	d.file = layer.name
+	d.si = si
+	d.sharedSI = true
+
+	for {
+		if err := d.Decode(m); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
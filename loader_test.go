@@ -0,0 +1,86 @@
+package protoyaml
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/tommie/protoyaml-go/internal/testproto"
+)
+
+func TestLoaderLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"conf.d/10-region.yaml": {Data: []byte(`amessage: {anint32: 2}
+arepeated_int32: [2]`)},
+		"conf.d/20-host.yaml": {Data: []byte(`astring: host
+---
+amessage:
+  astring: !delete`)},
+		"conf.d/README": {Data: []byte(`not yaml: [`)},
+	}
+
+	l := NewLoader()
+	l.AddReader("base.yaml", strings.NewReader(`astring: base
+amessage: {anint32: 1, astring: x}
+arepeated_int32: [1]`))
+	if err := l.AddGlob(fsys, "conf.d/*.yaml"); err != nil {
+		t.Fatalf("AddGlob failed: %v", err)
+	}
+
+	var got testproto.Message
+	si, err := l.Load(&got)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	want := &testproto.Message{
+		Astring:        "host",
+		Amessage:       &testproto.Message{Anint32: 2},
+		ArepeatedInt32: []int32{2},
+	}
+	if diff := cmp.Diff(want, &got, protocmp.Transform()); diff != "" {
+		t.Errorf("Load: +got, -want:\n%s", diff)
+	}
+
+	wantPos := map[string]Position{
+		"astring":            {"conf.d/20-host.yaml", 1, 1},
+		"amessage":           {"conf.d/20-host.yaml", 3, 1},
+		"amessage.anint32":   {"conf.d/10-region.yaml", 1, 12},
+		"arepeated_int32":    {"conf.d/10-region.yaml", 2, 1},
+		"arepeated_int32[0]": {"conf.d/10-region.yaml", 2, 19},
+	}
+	if diff := cmp.Diff(wantPos, si.Positions); diff != "" {
+		t.Errorf("Load SourceInfo: +got, -want:\n%s", diff)
+	}
+}
+
+func TestLoaderLoadConfigure(t *testing.T) {
+	l := NewLoader()
+	l.AddReader("a.yaml", strings.NewReader(`arepeated_int32: [1]`))
+	l.AddReader("b.yaml", strings.NewReader(`arepeated_int32: [2]`))
+	l.Configure(func(d *Decoder) { d.Patch(PatchMerge) })
+
+	var got testproto.Message
+	if _, err := l.Load(&got); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	want := &testproto.Message{ArepeatedInt32: []int32{1, 2}}
+	if diff := cmp.Diff(want, &got, protocmp.Transform()); diff != "" {
+		t.Errorf("Load: +got, -want:\n%s", diff)
+	}
+}
+
+func TestLoaderLoadError(t *testing.T) {
+	l := NewLoader()
+	l.AddReader("a.yaml", strings.NewReader(`astring: a`))
+	l.AddFile(fstest.MapFS{}, "missing.yaml")
+
+	var got testproto.Message
+	if _, err := l.Load(&got); err == nil || !strings.HasPrefix(err.Error(), "missing.yaml: ") {
+		t.Errorf("Load: got %v, want an error about missing.yaml", err)
+	}
+}
@@ -0,0 +1,172 @@
+package protoyaml
+
+import (
+	"bytes"
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+)
+
+// A PatchStrategy selects how repeated and map fields in a patch are
+// applied to fields that are already set.
+type PatchStrategy int
+
+const (
+	// PatchReplace replaces repeated and map fields as a whole.
+	PatchReplace PatchStrategy = iota
+
+	// PatchMerge works like proto.Merge: list elements are appended,
+	// and map entries are added or replaced.
+	PatchMerge
+)
+
+// DeleteTag is the YAML tag that marks a field, map entry or keyed
+// list element for deletion in patch mode, e.g. `port: !delete`.
+const DeleteTag = "!delete"
+
+// Patch enables patch mode, where each document is applied as a
+// partial update to the message passed to Decode. Fields not
+// mentioned in the document are left alone, and message fields are
+// merged recursively. Repeated and map fields are updated according
+// to the strategy, unless a key is set with PatchKey. A null value,
+// or the DeleteTag, clears a field or removes a map entry.
+//
+// Without patch mode, a key in the document replaces the field as a
+// whole.
+func (d *Decoder) Patch(s PatchStrategy) {
+	d.patch = true
+	d.patchStrategy = s
+}
+
+// PatchKey makes a repeated message field be merged by key in patch
+// mode. Each element in the document is merged into the existing
+// element with the same value in the key field, or appended if there
+// is none. An element tagged with DeleteTag is removed instead. The
+// key field must be a scalar written directly in each element.
+func (d *Decoder) PatchKey(field protoreflect.FullName, key protoreflect.Name) {
+	if d.patchKeys == nil {
+		d.patchKeys = map[protoreflect.FullName]protoreflect.Name{}
+	}
+	d.patchKeys[field] = key
+}
+
+// patchesInPlace returns true if a field that is already set should be
+// updated by the document, rather than replaced.
+func (d *Decoder) patchesInPlace(fd protoreflect.FieldDescriptor) bool {
+	switch {
+	case !d.patch:
+		return false
+	case fd.IsList():
+		_, keyed := d.patchKeys[fd.FullName()]
+		return keyed || d.patchStrategy == PatchMerge
+	case fd.IsMap():
+		return d.patchStrategy == PatchMerge
+	default:
+		return isMessageKind(fd) && !isKnownType(fd.Message())
+	}
+}
+
+// patchKeyField returns the key field of a repeated message field, or
+// nil if it is not merged by key.
+func (d *Decoder) patchKeyField(fd protoreflect.FieldDescriptor) (protoreflect.FieldDescriptor, error) {
+	name, ok := d.patchKeys[fd.FullName()]
+	if !d.patch || !ok {
+		return nil, nil
+	}
+	if !isMessageKind(fd) {
+		return nil, fmt.Errorf("protoyaml: patch key on non-message field: %s", fd.FullName())
+	}
+	kfd := fd.Message().Fields().ByName(name)
+	if kfd == nil || kfd.IsList() || kfd.IsMap() || isMessageKind(kfd) {
+		return nil, fmt.Errorf("protoyaml: patch key %s is not a scalar field in %s", name, fd.Message().FullName())
+	}
+	return kfd, nil
+}
+
+// decodeKeyedList merges a sequence into a list of messages, matching
+// elements by the key field kfd.
+func (d *Decoder) decodeKeyedList(l protoreflect.List, fd, kfd protoreflect.FieldDescriptor, v *yaml.Node) error {
+	parent := d.path
+	for _, n := range v.Content {
+		n, err := d.resolveNode(n)
+		if err != nil {
+			return err
+		}
+		kv, err := d.patchKeyValue(kfd, n)
+		if err != nil {
+			return err
+		}
+
+		i := 0
+		for ; i < l.Len(); i++ {
+			if equalScalars(l.Get(i).Message().Get(kfd), kv) {
+				break
+			}
+		}
+		d.path = listPath(parent, i)
+
+		if isDeletion(n) {
+			if i < l.Len() {
+				for j := i; j+1 < l.Len(); j++ {
+					l.Set(j, l.Get(j+1))
+				}
+				l.Truncate(l.Len() - 1)
+			}
+			continue
+		}
+
+		var m protoreflect.Message
+		if i < l.Len() {
+			m = l.Get(i).Message()
+		} else {
+			m = l.AppendMutable().Message()
+		}
+		d.si.record(d.path, d.nodePosition(n))
+		if err := d.decodeMessage(m, n, false); err != nil {
+			return err
+		}
+	}
+	d.path = parent
+	return nil
+}
+
+// patchKeyValue finds the value of the key field in a list element.
+func (d *Decoder) patchKeyValue(kfd protoreflect.FieldDescriptor, n *yaml.Node) (protoreflect.Value, error) {
+	n, err := d.resolveNode(n)
+	if err != nil {
+		return protoreflect.Value{}, err
+	}
+	if d.isRef(n) {
+		var kv protoreflect.Value
+		err := d.decodeRef(n, func(n *yaml.Node) error {
+			var err error
+			kv, err = d.patchKeyValue(kfd, n)
+			return err
+		})
+		return kv, err
+	}
+	if n.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == string(kfd.Name()) {
+				return d.decodeValue(kfd, n.Content[i+1])
+			}
+		}
+	}
+	return protoreflect.Value{}, fmt.Errorf("protoyaml: missing patch key %s in element of %s at line %d", kfd.Name(), kfd.ContainingMessage().FullName(), n.Line)
+}
+
+// isDeletion returns true if a value marks something for deletion in
+// patch mode.
+func isDeletion(n *yaml.Node) bool {
+	return n.Tag == DeleteTag || n.ShortTag() == "!!null"
+}
+
+// equalScalars returns true if two non-message values are equal.
+func equalScalars(a, b protoreflect.Value) bool {
+	if ab, ok := a.Interface().([]byte); ok {
+		bb, ok := b.Interface().([]byte)
+		return ok && bytes.Equal(ab, bb)
+	}
+	return a.Interface() == b.Interface()
+}
@@ -0,0 +1,151 @@
+package protoyaml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/tommie/protoyaml-go/internal/testproto"
+)
+
+func TestDecoderPatch(t *testing.T) {
+	const base = `astring: a
+anint32: 1
+amessage: {anint32: 1, astring: x}
+arepeated_int32: [1]
+astring_int32_map: {a: 1}
+arepeated_message: [{astring: a, anint32: 1}, {astring: b, anint32: 2}, {astring: c}]`
+
+	tsts := []struct {
+		Name  string
+		Patch string
+		Setup func(d *Decoder)
+		Want  *testproto.Message
+	}{
+		{
+			"noPatch",
+			`{amessage: {anint32: 2}, arepeated_int32: [2]}`,
+			func(d *Decoder) {},
+			&testproto.Message{
+				Astring:          "a",
+				Anint32:          1,
+				Amessage:         &testproto.Message{Anint32: 2},
+				ArepeatedInt32:   []int32{2},
+				AstringInt32Map:  map[string]int32{"a": 1},
+				ArepeatedMessage: []*testproto.Message{{Astring: "a", Anint32: 1}, {Astring: "b", Anint32: 2}, {Astring: "c"}},
+			},
+		},
+		{
+			"replace",
+			`{amessage: {anint32: 2}, arepeated_int32: [2], astring_int32_map: {b: 2}}`,
+			func(d *Decoder) { d.Patch(PatchReplace) },
+			&testproto.Message{
+				Astring:          "a",
+				Anint32:          1,
+				Amessage:         &testproto.Message{Anint32: 2, Astring: "x"},
+				ArepeatedInt32:   []int32{2},
+				AstringInt32Map:  map[string]int32{"b": 2},
+				ArepeatedMessage: []*testproto.Message{{Astring: "a", Anint32: 1}, {Astring: "b", Anint32: 2}, {Astring: "c"}},
+			},
+		},
+		{
+			"merge",
+			`{arepeated_int32: [2], astring_int32_map: {b: 2}, arepeated_message: [{astring: d}]}`,
+			func(d *Decoder) { d.Patch(PatchMerge) },
+			&testproto.Message{
+				Astring:          "a",
+				Anint32:          1,
+				Amessage:         &testproto.Message{Anint32: 1, Astring: "x"},
+				ArepeatedInt32:   []int32{1, 2},
+				AstringInt32Map:  map[string]int32{"a": 1, "b": 2},
+				ArepeatedMessage: []*testproto.Message{{Astring: "a", Anint32: 1}, {Astring: "b", Anint32: 2}, {Astring: "c"}, {Astring: "d"}},
+			},
+		},
+		{
+			"delete",
+			`astring: null
+amessage: !delete
+astring_int32_map: {a: ~}
+arepeated_message: !delete`,
+			func(d *Decoder) { d.Patch(PatchMerge) },
+			&testproto.Message{
+				Anint32:         1,
+				ArepeatedInt32:  []int32{1},
+				AstringInt32Map: map[string]int32{},
+			},
+		},
+		{
+			"keyed",
+			`arepeated_message: [{astring: b, anint32: 3}, {astring: d}, !delete {astring: a}]`,
+			func(d *Decoder) {
+				d.Patch(PatchReplace)
+				d.PatchKey("protoyaml.test.Message.arepeated_message", "astring")
+			},
+			&testproto.Message{
+				Astring:          "a",
+				Anint32:          1,
+				Amessage:         &testproto.Message{Anint32: 1, Astring: "x"},
+				ArepeatedInt32:   []int32{1},
+				AstringInt32Map:  map[string]int32{"a": 1},
+				ArepeatedMessage: []*testproto.Message{{Astring: "b", Anint32: 3}, {Astring: "c"}, {Astring: "d"}},
+			},
+		},
+		{
+			"mergeKey",
+			`{<< : {anint32: 2, astring: b}, astring: c}`,
+			func(d *Decoder) { d.Patch(PatchReplace) },
+			&testproto.Message{
+				Astring:          "c",
+				Anint32:          2,
+				Amessage:         &testproto.Message{Anint32: 1, Astring: "x"},
+				ArepeatedInt32:   []int32{1},
+				AstringInt32Map:  map[string]int32{"a": 1},
+				ArepeatedMessage: []*testproto.Message{{Astring: "a", Anint32: 1}, {Astring: "b", Anint32: 2}, {Astring: "c"}},
+			},
+		},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Name, func(t *testing.T) {
+			var got testproto.Message
+			if err := Unmarshal([]byte(base), &got); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+
+			d := NewDecoder(strings.NewReader(tst.Patch))
+			tst.Setup(d)
+			if err := d.Decode(&got); err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+
+			if diff := cmp.Diff(tst.Want, &got, protocmp.Transform()); diff != "" {
+				t.Errorf("Decode: +got, -want:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecoderPatchKeyError(t *testing.T) {
+	tsts := []struct {
+		Name  string
+		Patch string
+		Key   string
+	}{
+		{"missingKey", `arepeated_message: [{anint32: 1}]`, "astring"},
+		{"nonScalarKey", `arepeated_message: [{astring: a}]`, "amessage"},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Name, func(t *testing.T) {
+			d := NewDecoder(strings.NewReader(tst.Patch))
+			d.Patch(PatchReplace)
+			d.PatchKey("protoyaml.test.Message.arepeated_message", protoreflect.Name(tst.Key))
+
+			var got testproto.Message
+			if err := d.Decode(&got); err == nil {
+				t.Errorf("Decode: got nil error")
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package protoyaml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Field paths identify a value inside a message. They use Protobuf
+// names, list indices and map keys, e.g.
+// `amessage.arepeated_message[2].astring_int32_map["key"]`. Extension
+// fields are written as "(full.name)".
+
+// fieldPath returns the path of a field in the message at parent.
+func fieldPath(parent string, fd protoreflect.FieldDescriptor) string {
+	name := string(fd.Name())
+	if fd.IsExtension() {
+		name = "(" + string(fd.FullName()) + ")"
+	}
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// keyPath returns the path a mapping key would have as a field in the
+// message at parent, for keys that don't name a field. Extension keys,
+// written as "[full.name]", use the "(full.name)" path syntax.
+func keyPath(parent, key string) string {
+	if strings.HasPrefix(key, "[") && strings.HasSuffix(key, "]") {
+		key = "(" + key[1:len(key)-1] + ")"
+	}
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// listPath returns the path of a list element in the field at parent.
+func listPath(parent string, i int) string {
+	return parent + "[" + strconv.Itoa(i) + "]"
+}
+
+// mapPath returns the path of a map entry in the field at parent.
+// String keys are quoted.
+func mapPath(parent string, k protoreflect.MapKey) string {
+	if s, ok := k.Interface().(string); ok {
+		return parent + "[" + strconv.Quote(s) + "]"
+	}
+	return parent + "[" + k.String() + "]"
+}
+
+// isPathPrefix returns true if path equals prefix, or is below it.
+func isPathPrefix(prefix, path string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	if len(path) == len(prefix) || prefix == "" {
+		return true
+	}
+	return path[len(prefix)] == '.' || path[len(prefix)] == '['
+}
+
+// A pathStep is one component of a parsed field path.
+type pathStep struct {
+	// name is a field name, or "(full.name)" for an extension field.
+	name string
+
+	// key is a list index or a map key, unquoted. Only used if isKey
+	// is true.
+	key   string
+	isKey bool
+}
+
+// parsePath splits a field path into its components.
+func parsePath(s string) ([]pathStep, error) {
+	var steps []pathStep
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '[':
+			end, key, err := parsePathKey(s, i+1)
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, pathStep{key: key, isKey: true})
+			i = end
+
+		case s[i] == '.' && len(steps) > 0:
+			i++
+			if i == len(s) {
+				return nil, fmt.Errorf("protoyaml: field path ends with a dot: %s", s)
+			}
+			fallthrough
+
+		default:
+			if len(steps) > 0 && s[i-1] != '.' {
+				return nil, fmt.Errorf("protoyaml: missing dot at offset %d in field path: %s", i, s)
+			}
+			end := i
+			if s[i] == '(' {
+				end = strings.IndexByte(s[i:], ')')
+				if end < 0 {
+					return nil, fmt.Errorf("protoyaml: unterminated extension name in field path: %s", s)
+				}
+				end += i + 1
+			} else {
+				for end < len(s) && s[end] != '.' && s[end] != '[' {
+					end++
+				}
+			}
+			if end == i {
+				return nil, fmt.Errorf("protoyaml: empty field name at offset %d in field path: %s", i, s)
+			}
+			steps = append(steps, pathStep{name: s[i:end]})
+			i = end
+		}
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("protoyaml: empty field path")
+	}
+	if steps[0].isKey {
+		return nil, fmt.Errorf("protoyaml: field path must start with a field name: %s", s)
+	}
+	return steps, nil
+}
+
+// parsePathKey parses a list index or map key, starting after the
+// opening bracket. Returns the offset after the closing bracket.
+func parsePathKey(s string, i int) (int, string, error) {
+	if i < len(s) && s[i] == '"' {
+		q, err := strconv.QuotedPrefix(s[i:])
+		if err != nil {
+			return 0, "", fmt.Errorf("protoyaml: invalid quoted key at offset %d in field path: %s", i, s)
+		}
+		key, _ := strconv.Unquote(q)
+		i += len(q)
+		if i >= len(s) || s[i] != ']' {
+			return 0, "", fmt.Errorf("protoyaml: missing ] at offset %d in field path: %s", i, s)
+		}
+		return i + 1, key, nil
+	}
+
+	end := strings.IndexByte(s[i:], ']')
+	if end < 0 {
+		return 0, "", fmt.Errorf("protoyaml: missing ] in field path: %s", s)
+	}
+	return i + end + 1, s[i : i+end], nil
+}
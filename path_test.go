@@ -0,0 +1,61 @@
+package protoyaml
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParsePath(t *testing.T) {
+	tsts := []struct {
+		Name string
+		Path string
+		Want []pathStep
+	}{
+		{"field", "a", []pathStep{{name: "a"}}},
+		{"nested", "a.b", []pathStep{{name: "a"}, {name: "b"}}},
+		{"index", "a[2].b", []pathStep{{name: "a"}, {key: "2", isKey: true}, {name: "b"}}},
+		{"quotedKey", `a["x.]"]`, []pathStep{{name: "a"}, {key: "x.]", isKey: true}}},
+		{"plainKey", `a[true][-1]`, []pathStep{{name: "a"}, {key: "true", isKey: true}, {key: "-1", isKey: true}}},
+		{"extension", "a.(b.c).d", []pathStep{{name: "a"}, {name: "(b.c)"}, {name: "d"}}},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Name, func(t *testing.T) {
+			got, err := parsePath(tst.Path)
+			if err != nil {
+				t.Fatalf("parsePath failed: %v", err)
+			}
+
+			if diff := cmp.Diff(tst.Want, got, cmp.AllowUnexported(pathStep{})); diff != "" {
+				t.Errorf("parsePath: +got, -want:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParsePathError(t *testing.T) {
+	for _, path := range []string{"", "[0]", "a.", "a..b", "a[0", `a["x]`, "a[0]b", "(a.b", ".a"} {
+		if _, err := parsePath(path); err == nil {
+			t.Errorf("parsePath(%q): got nil error", path)
+		}
+	}
+}
+
+func TestIsPathPrefix(t *testing.T) {
+	tsts := []struct {
+		Prefix, Path string
+		Want         bool
+	}{
+		{"", "a", true},
+		{"a", "a", true},
+		{"a", "a.b", true},
+		{"a", "a[0]", true},
+		{"a", "ab", false},
+		{"a.b", "a", false},
+	}
+	for _, tst := range tsts {
+		if got := isPathPrefix(tst.Prefix, tst.Path); got != tst.Want {
+			t.Errorf("isPathPrefix(%q, %q): got %v, want %v", tst.Prefix, tst.Path, got, tst.Want)
+		}
+	}
+}
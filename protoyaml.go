@@ -1,4 +1,5 @@
-// Package protoyaml contains a YAML decoder in the spirit of what
+// Package protoyaml contains a YAML encoder and decoder in the spirit
+// of what
 // https://pkg.go.dev/google.golang.org/protobuf/encoding/protojson is
 // for JSON.
 package protoyaml
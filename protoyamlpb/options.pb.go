@@ -0,0 +1,150 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: protoyamlpb/options.proto
+
+package protoyamlpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Options that change how protoyaml decodes a field, e.g.
+//
+//	string listen_address = 1 [(protoyaml.field).aliases = "listen"];
+type FieldOptions struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Former names of the field. A key using an alias is decoded as the
+	// field, with a warning.
+	Aliases       []string `protobuf:"bytes,1,rep,name=aliases,proto3" json:"aliases,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FieldOptions) Reset() {
+	*x = FieldOptions{}
+	mi := &file_protoyamlpb_options_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FieldOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FieldOptions) ProtoMessage() {}
+
+func (x *FieldOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_protoyamlpb_options_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FieldOptions.ProtoReflect.Descriptor instead.
+func (*FieldOptions) Descriptor() ([]byte, []int) {
+	return file_protoyamlpb_options_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *FieldOptions) GetAliases() []string {
+	if x != nil {
+		return x.Aliases
+	}
+	return nil
+}
+
+var file_protoyamlpb_options_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.FieldOptions)(nil),
+		ExtensionType: (*FieldOptions)(nil),
+		Field:         50817,
+		Name:          "protoyaml.field",
+		Tag:           "bytes,50817,opt,name=field",
+		Filename:      "protoyamlpb/options.proto",
+	},
+}
+
+// Extension fields to descriptorpb.FieldOptions.
+var (
+	// optional protoyaml.FieldOptions field = 50817;
+	E_Field = &file_protoyamlpb_options_proto_extTypes[0]
+)
+
+var File_protoyamlpb_options_proto protoreflect.FileDescriptor
+
+const file_protoyamlpb_options_proto_rawDesc = "" +
+	"\n" +
+	"\x19protoyamlpb/options.proto\x12\tprotoyaml\x1a google/protobuf/descriptor.proto\"(\n" +
+	"\fFieldOptions\x12\x18\n" +
+	"\aaliases\x18\x01 \x03(\tR\aaliases:N\n" +
+	"\x05field\x12\x1d.google.protobuf.FieldOptions\x18\x81\x8d\x03 \x01(\v2\x17.protoyaml.FieldOptionsR\x05fieldB,Z*github.com/tommie/protoyaml-go/protoyamlpbb\x06proto3"
+
+var (
+	file_protoyamlpb_options_proto_rawDescOnce sync.Once
+	file_protoyamlpb_options_proto_rawDescData []byte
+)
+
+func file_protoyamlpb_options_proto_rawDescGZIP() []byte {
+	file_protoyamlpb_options_proto_rawDescOnce.Do(func() {
+		file_protoyamlpb_options_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_protoyamlpb_options_proto_rawDesc), len(file_protoyamlpb_options_proto_rawDesc)))
+	})
+	return file_protoyamlpb_options_proto_rawDescData
+}
+
+var file_protoyamlpb_options_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_protoyamlpb_options_proto_goTypes = []any{
+	(*FieldOptions)(nil),              // 0: protoyaml.FieldOptions
+	(*descriptorpb.FieldOptions)(nil), // 1: google.protobuf.FieldOptions
+}
+var file_protoyamlpb_options_proto_depIdxs = []int32{
+	1, // 0: protoyaml.field:extendee -> google.protobuf.FieldOptions
+	0, // 1: protoyaml.field:type_name -> protoyaml.FieldOptions
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	1, // [1:2] is the sub-list for extension type_name
+	0, // [0:1] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_protoyamlpb_options_proto_init() }
+func file_protoyamlpb_options_proto_init() {
+	if File_protoyamlpb_options_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_protoyamlpb_options_proto_rawDesc), len(file_protoyamlpb_options_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 1,
+			NumServices:   0,
+		},
+		GoTypes:           file_protoyamlpb_options_proto_goTypes,
+		DependencyIndexes: file_protoyamlpb_options_proto_depIdxs,
+		MessageInfos:      file_protoyamlpb_options_proto_msgTypes,
+		ExtensionInfos:    file_protoyamlpb_options_proto_extTypes,
+	}.Build()
+	File_protoyamlpb_options_proto = out.File
+	file_protoyamlpb_options_proto_goTypes = nil
+	file_protoyamlpb_options_proto_depIdxs = nil
+}
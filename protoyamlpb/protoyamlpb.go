@@ -0,0 +1,5 @@
+// Package protoyamlpb contains the Protobuf options understood by
+// protoyaml. Import "protoyamlpb/options.proto" to use them.
+//
+//go:generate sh -c "cd .. && protoc --plugin=\"$(go env GOPATH)/bin/protoc-gen-go\" --go_out=. --go_opt=paths=source_relative protoyamlpb/options.proto"
+package protoyamlpb
@@ -0,0 +1,166 @@
+package protoyaml
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RefKey is the mapping key of a JSON-Reference-style reference.
+const RefKey = "$ref"
+
+// ResolveRefs enables references to nodes in other files, or other
+// parts of the same document, e.g. `tls: {$ref: "common.yaml#/tls"}`.
+// A mapping whose only key is RefKey is decoded as if the referenced
+// node had been written in its place. The reference is a file name,
+// relative to the referencing file, followed by an optional JSON
+// Pointer (RFC 6901) into the first document of the file. A reference
+// starting with "#" points into the current file. Files are read from
+// fsys, and cached for the lifetime of the decoder. The name of the
+// top-level file is taken from FileName or RecordSourceInfo.
+func (d *Decoder) ResolveRefs(fsys fs.FS) {
+	d.refFS = fsys
+	d.refFiles = map[string]*yaml.Node{}
+}
+
+// A refFrame is a reference being decoded.
+type refFrame struct {
+	file   string
+	root   *yaml.Node
+	target *yaml.Node
+}
+
+// isRef returns true if n is a reference mapping, and references are
+// enabled.
+func (d *Decoder) isRef(n *yaml.Node) bool {
+	return d.refFS != nil && n.Kind == yaml.MappingNode && len(n.Content) >= 2 && n.Content[0].Value == RefKey
+}
+
+// decodeRef resolves the reference n, and calls f with the referenced
+// node. Errors from f are annotated with both locations.
+func (d *Decoder) decodeRef(n *yaml.Node, f func(*yaml.Node) error) error {
+	if len(n.Content) != 2 {
+		return d.nodeErrorf(n, "%s must be the only key in a mapping", RefKey)
+	}
+	ref := n.Content[1].Value
+	file, root, target, err := d.resolveRef(ref)
+	if err != nil {
+		return d.nodeErrorf(n, "%s %s: %v", RefKey, ref, err)
+	}
+
+	for _, fr := range d.refStack {
+		if fr.target == target {
+			return d.nodeErrorf(n, "%s %s: reference cycle", RefKey, ref)
+		}
+	}
+
+	from := d.nodePosition(n)
+	d.refStack = append(d.refStack, refFrame{file: file, root: root, target: target})
+	err = f(target)
+	d.refStack = d.refStack[:len(d.refStack)-1]
+	if err != nil {
+		to := Position{File: file, Line: target.Line, Column: target.Column}
+		return fmt.Errorf("protoyaml: %v: in %s %s (%v): %w", from, RefKey, ref, to, err)
+	}
+	return nil
+}
+
+// resolveRef returns the file name, document root and node referenced
+// by ref, relative to the file currently being decoded.
+func (d *Decoder) resolveRef(ref string) (string, *yaml.Node, *yaml.Node, error) {
+	name, ptr, _ := strings.Cut(ref, "#")
+
+	file := d.file
+	root := d.root
+	if len(d.refStack) > 0 {
+		fr := d.refStack[len(d.refStack)-1]
+		file = fr.file
+		root = fr.root
+	}
+	if name != "" {
+		file = cleanFSPath(path.Join(path.Dir(file), name))
+		var err error
+		root, err = d.loadRefFile(file)
+		if err != nil {
+			return "", nil, nil, err
+		}
+	}
+	if root == nil {
+		return "", nil, nil, fmt.Errorf("no document to resolve %q in", ref)
+	}
+
+	n, err := evalJSONPointer(root, ptr)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return file, root, n, nil
+}
+
+// loadRefFile returns the root node of the first document in a file.
+func (d *Decoder) loadRefFile(name string) (*yaml.Node, error) {
+	if n, ok := d.refFiles[name]; ok {
+		return n, nil
+	}
+
+	bs, err := fs.ReadFile(d.refFS, name)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(bs, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, fmt.Errorf("%s: empty document", name)
+	}
+	d.refFiles[name] = doc.Content[0]
+	return doc.Content[0], nil
+}
+
+// evalJSONPointer returns the node at the JSON Pointer ptr, relative to
+// root. An empty pointer refers to root.
+func evalJSONPointer(root *yaml.Node, ptr string) (*yaml.Node, error) {
+	if ptr == "" {
+		return root, nil
+	}
+	if !strings.HasPrefix(ptr, "/") {
+		return nil, fmt.Errorf("JSON pointer must start with a slash: %s", ptr)
+	}
+
+	n := root
+	for _, tok := range strings.Split(ptr[1:], "/") {
+		tok = strings.NewReplacer("~1", "/", "~0", "~").Replace(tok)
+		if n.Kind == yaml.AliasNode {
+			n = n.Alias
+		}
+		switch n.Kind {
+		case yaml.MappingNode:
+			var next *yaml.Node
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if n.Content[i].Value == tok {
+					next = n.Content[i+1]
+					break
+				}
+			}
+			if next == nil {
+				return nil, fmt.Errorf("no key %q in %s", tok, ptr)
+			}
+			n = next
+
+		case yaml.SequenceNode:
+			i, err := strconv.Atoi(tok)
+			if err != nil || i < 0 || i >= len(n.Content) {
+				return nil, fmt.Errorf("invalid index %q in %s", tok, ptr)
+			}
+			n = n.Content[i]
+
+		default:
+			return nil, fmt.Errorf("cannot index a scalar with %q in %s", tok, ptr)
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,145 @@
+package protoyaml
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tommie/protoyaml-go/internal/testproto"
+)
+
+func TestDecoderResolveRefs(t *testing.T) {
+	fsys := fstest.MapFS{
+		"common.yaml":     {Data: []byte("defaults:\n  anint32: 42\n  amessage: {$ref: 'sub/leaf.yaml#/leaf'}\nlist: [1, 2]\n")},
+		"sub/leaf.yaml":   {Data: []byte("leaf: {astring: leaf, amessage: {$ref: '#/other'}}\nother: {abool: true}\n")},
+		"escaped.yaml":    {Data: []byte("a/b: {anint32: 1}\nm~n: {anint32: 2}\n")},
+		"sub/string.yaml": {Data: []byte("hello\n")},
+	}
+
+	tsts := []struct {
+		Name string
+		YAML string
+		Want *testproto.Message
+	}{
+		{"file", `amessage: {$ref: common.yaml#/defaults}`, &testproto.Message{Amessage: &testproto.Message{Anint32: 42, Amessage: &testproto.Message{Astring: "leaf", Amessage: &testproto.Message{Abool: true}}}}},
+		{"wholeFile", `astring: {$ref: sub/string.yaml}`, &testproto.Message{Astring: "hello"}},
+		{"list", `arepeated_int32: {$ref: common.yaml#/list}`, &testproto.Message{ArepeatedInt32: []int32{1, 2}}},
+		{"listElement", `arepeated_int32: [{$ref: common.yaml#/list/1}, 3]`, &testproto.Message{ArepeatedInt32: []int32{2, 3}}},
+		{"sameDocument", "amessage: {$ref: '#/astring_message_map/x'}\nastring_message_map: {x: {anint32: 7}}", &testproto.Message{Amessage: &testproto.Message{Anint32: 7}, AstringMessageMap: map[string]*testproto.Message{"x": {Anint32: 7}}}},
+		{"nestedSameDocument", "amessage: {$ref: '#/arepeated_message/0'}\narepeated_message: [{$ref: '#/arepeated_message/1'}, {anint32: 7}]", &testproto.Message{Amessage: &testproto.Message{Anint32: 7}, ArepeatedMessage: []*testproto.Message{{Anint32: 7}, {Anint32: 7}}}},
+		{"escapes", `arepeated_message: [{$ref: 'escaped.yaml#/a~1b'}, {$ref: 'escaped.yaml#/m~0n'}]`, &testproto.Message{ArepeatedMessage: []*testproto.Message{{Anint32: 1}, {Anint32: 2}}}},
+		{"root", `$ref: common.yaml#/defaults`, &testproto.Message{Anint32: 42, Amessage: &testproto.Message{Astring: "leaf", Amessage: &testproto.Message{Abool: true}}}},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Name, func(t *testing.T) {
+			d := NewDecoder(strings.NewReader(tst.YAML))
+			d.ResolveRefs(fsys)
+
+			var got testproto.Message
+			if err := d.Decode(&got); err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+
+			if diff := cmp.Diff(tst.Want, &got, protocmp.Transform()); diff != "" {
+				t.Errorf("Decode: +got, -want:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecoderResolveRefsSourceInfo(t *testing.T) {
+	fsys := fstest.MapFS{
+		"common.yaml": {Data: []byte("tls:\n  astring: cert\n")},
+	}
+
+	d := NewDecoder(strings.NewReader("anint32: 1\namessage: {$ref: common.yaml#/tls}\n"))
+	d.RecordSourceInfo("main.yaml")
+	d.ResolveRefs(fsys)
+
+	var got testproto.Message
+	if err := d.Decode(&got); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	want := map[string]Position{
+		"anint32":          {File: "main.yaml", Line: 1, Column: 1},
+		"amessage":         {File: "main.yaml", Line: 2, Column: 1},
+		"amessage.astring": {File: "common.yaml", Line: 2, Column: 3},
+	}
+	if diff := cmp.Diff(want, d.SourceInfo().Positions); diff != "" {
+		t.Errorf("SourceInfo: +got, -want:\n%s", diff)
+	}
+}
+
+func TestDecoderResolveRefsError(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.yaml":  {Data: []byte("amessage: {$ref: sub/b.yaml}\n")},
+		"sub/b.yaml": {Data: []byte("amessage: {$ref: ../main.yaml}\n")},
+		"bad.yaml":   {Data: []byte("x:\n  anint32: notanumber\n")},
+	}
+
+	tsts := []struct {
+		Name string
+		YAML string
+		Want string
+	}{
+		{"missingFile", `amessage: {$ref: missing.yaml}`, "protoyaml: main.yaml:1:11: $ref missing.yaml: open missing.yaml: file does not exist"},
+		{"missingKey", `amessage: {$ref: '#/nope'}`, `protoyaml: main.yaml:1:11: $ref #/nope: no key "nope" in /nope`},
+		{"extraKey", `amessage: {$ref: bad.yaml#/x, anint32: 1}`, "protoyaml: main.yaml:1:11: $ref must be the only key in a mapping"},
+		{"cycle", `amessage: {$ref: sub/b.yaml}`, "protoyaml: main.yaml:1:11: in $ref sub/b.yaml (sub/b.yaml:1:1): protoyaml: sub/b.yaml:1:11: in $ref ../main.yaml (main.yaml:1:1): protoyaml: main.yaml:1:11: $ref sub/b.yaml: reference cycle"},
+		{"badValue", `amessage: {$ref: bad.yaml#/x}`, "protoyaml: main.yaml:1:11: in $ref bad.yaml#/x (bad.yaml:2:3): protoyaml: bad.yaml:2:12: cannot unmarshal !!str `notanumber` into int32"},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Name, func(t *testing.T) {
+			d := NewDecoder(strings.NewReader(tst.YAML))
+			d.RecordSourceInfo("main.yaml")
+			d.ResolveRefs(fsys)
+
+			var got testproto.Message
+			err := d.Decode(&got)
+			if err == nil || err.Error() != tst.Want {
+				t.Errorf("Decode: got %v, want %q", err, tst.Want)
+			}
+		})
+	}
+}
+
+func TestEvalJSONPointer(t *testing.T) {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte("a: [x, {b: y}]\n"), &root); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	tsts := []struct {
+		Ptr  string
+		Want string
+		Err  string
+	}{
+		{Ptr: "/a/0", Want: "x"},
+		{Ptr: "/a/1/b", Want: "y"},
+		{Ptr: "a", Err: "JSON pointer must start with a slash: a"},
+		{Ptr: "/a/2", Err: `invalid index "2" in /a/2`},
+		{Ptr: "/a/0/c", Err: `cannot index a scalar with "c" in /a/0/c`},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Ptr, func(t *testing.T) {
+			n, err := evalJSONPointer(root.Content[0], tst.Ptr)
+			if tst.Err != "" {
+				if err == nil || err.Error() != tst.Err {
+					t.Fatalf("evalJSONPointer: got %v, want %q", err, tst.Err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("evalJSONPointer failed: %v", err)
+			}
+			if n.Value != tst.Want {
+				t.Errorf("evalJSONPointer: got %q, want %q", n.Value, tst.Want)
+			}
+		})
+	}
+}
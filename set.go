@@ -0,0 +1,402 @@
+package protoyaml
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"gopkg.in/yaml.v3"
+)
+
+// GetPath returns the value at a field path in m, e.g.
+// `amessage.arepeated_message[2].astring_int32_map["key"]`. Unset
+// fields have their default value. It is an error if a list index or
+// map key does not exist. Extension fields in the path must be set in
+// m, or be in protoregistry.GlobalTypes. Use Decoder.GetPath for other
+// resolvers.
+func GetPath(m protoreflect.ProtoMessage, path string) (protoreflect.Value, error) {
+	return NewDecoder(nil).GetPath(m, path)
+}
+
+// GetPath is like the GetPath function, but resolves extension fields
+// in the path through the extension type resolver of the decoder.
+func (d *Decoder) GetPath(m protoreflect.ProtoMessage, path string) (protoreflect.Value, error) {
+	var v protoreflect.Value
+	err := walkPath(m.ProtoReflect(), path, d.xr, false, func(t pathTarget) error {
+		v = t.get()
+		return nil
+	})
+	return v, err
+}
+
+// GetPathNode returns the YAML representation of the value at a field
+// path in m, see GetPath.
+func GetPathNode(m protoreflect.ProtoMessage, path string) (*yaml.Node, error) {
+	return NewDecoder(nil).GetPathNode(m, path)
+}
+
+// GetPathNode is like the GetPathNode function, but resolves extension
+// fields like Decoder.GetPath, and anypb.Any types through the message
+// type resolver of the decoder.
+func (d *Decoder) GetPathNode(m protoreflect.ProtoMessage, path string) (*yaml.Node, error) {
+	var n *yaml.Node
+	err := walkPath(m.ProtoReflect(), path, d.xr, false, func(t pathTarget) error {
+		var err error
+		e := NewEncoder(nil)
+		e.MessageTypeResolver(d.r)
+		switch {
+		case t.key.IsValid():
+			n, err = e.encodeSingular(t.fd.MapValue(), t.get())
+		case t.index >= 0:
+			n, err = e.encodeSingular(t.fd, t.get())
+		default:
+			n, err = e.encodeField(t.fd, t.get())
+		}
+		return err
+	})
+	return n, err
+}
+
+// SetPath sets the value at a field path in m, creating parent
+// messages and map entries as needed. A list index may be the length
+// of the list, to append. Scalar values are text converted like the
+// Decoder converts plain YAML scalars, so `port=80` and `level=HIGH`
+// work. Messages, lists and maps are given as YAML, e.g.
+// `backend={address: a}` or `hosts=[a, b]`, and replace the old value.
+// On error, m is left unchanged.
+//
+// Extension fields in the path must already be set in m, or be in
+// protoregistry.GlobalTypes. Use Decoder.SetPath for other resolvers.
+func SetPath(m protoreflect.ProtoMessage, path, value string) error {
+	return NewDecoder(nil).SetPath(m, path, value)
+}
+
+// SetPath is like the SetPath function, but resolves extension fields
+// in the path through the resolver of the decoder, and decodes the
+// value with the configuration of the decoder, e.g. its resolvers,
+// tag handlers and environment expansion.
+func (d *Decoder) SetPath(m protoreflect.ProtoMessage, path, value string) error {
+	return walkPath(m.ProtoReflect(), path, d.xr, true, func(t pathTarget) error {
+		if err := t.set(d.pathDecoder(), value); err != nil {
+			// The position would be in the value string.
+			var derr *DecodeError
+			if errors.As(err, &derr) {
+				err = derr.Err
+			}
+			return fmt.Errorf("protoyaml: %s: %s", t.path, strings.TrimPrefix(err.Error(), "protoyaml: "))
+		}
+		return nil
+	})
+}
+
+// SetPathNode sets the value at a field path in a YAML document of a
+// md message, see SetPath. Only the changed values are rewritten, like
+// Encoder.UpdateDocument does.
+func SetPathNode(n *yaml.Node, md protoreflect.MessageDescriptor, path, value string) error {
+	return NewDecoder(nil).SetPathNode(n, md, path, value)
+}
+
+// SetPathNode is like the SetPathNode function, but decodes the
+// document and the value with the configuration of the decoder, e.g.
+// its resolvers and tag handlers.
+func (d *Decoder) SetPathNode(n *yaml.Node, md protoreflect.MessageDescriptor, path, value string) error {
+	root, err := documentRoot(n)
+	if err != nil {
+		return err
+	}
+	m := dynamicpb.NewMessage(md)
+	if err := d.pathDecoder().decodeRoot(root, m); err != nil {
+		return err
+	}
+	doc := &Document{node: n, m: cloneMessage(m)}
+	if err := d.SetPath(m, path, value); err != nil {
+		return err
+	}
+	e := NewEncoder(nil)
+	e.MessageTypeResolver(d.r)
+	return e.UpdateDocument(doc, m)
+}
+
+// pathDecoder returns a decoder for documents and values of field
+// paths, with the configuration of d, but none of its per-document
+// state. Required fields are not checked, and values replace the old
+// ones, instead of being patched in.
+func (d *Decoder) pathDecoder() *Decoder {
+	pd := *d
+	pd.yd = nil
+	pd.allowPartial = true
+	pd.allErrors = false
+	pd.errs = nil
+	pd.si = nil
+	pd.sharedSI = false
+	pd.path = ""
+	pd.refStack = nil
+	pd.root = nil
+	pd.tagged = nil
+	pd.patch = false
+	return &pd
+}
+
+// A pathTarget is the value a field path points to: a field, or an
+// element of a list or map field.
+type pathTarget struct {
+	m    protoreflect.Message
+	fd   protoreflect.FieldDescriptor
+	path string
+
+	// index is the list index, or -1.
+	index int
+
+	// key is the map key, if valid.
+	key protoreflect.MapKey
+}
+
+// get returns the value of the target.
+func (t pathTarget) get() protoreflect.Value {
+	switch {
+	case t.key.IsValid():
+		return t.m.Get(t.fd).Map().Get(t.key)
+	case t.index >= 0:
+		return t.m.Get(t.fd).List().Get(t.index)
+	default:
+		return t.m.Get(t.fd)
+	}
+}
+
+// set replaces the value of the target by the value decoded from s.
+// The target is only changed if s decodes.
+func (t pathTarget) set(d *Decoder, s string) error {
+	fd := t.fd
+	if t.key.IsValid() {
+		fd = fd.MapValue()
+	}
+	singular := t.key.IsValid() || t.index >= 0 || (!fd.IsList() && !fd.IsMap())
+
+	var pv protoreflect.Value
+	if singular && !isMessageKind(fd) {
+		var err error
+		pv, err = d.decodeValue(fd, &yaml.Node{Kind: yaml.ScalarNode, Value: s})
+		if err != nil {
+			return err
+		}
+	} else {
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(s), &doc); err != nil {
+			return err
+		}
+		if doc.Kind != yaml.DocumentNode {
+			return fmt.Errorf("protoyaml: empty value for %s", fd.FullName())
+		}
+		n := doc.Content[0]
+		if !singular {
+			tmp := t.m.New()
+			if err := d.decodeField(tmp, fd, n); err != nil {
+				return err
+			}
+			if tmp.Has(fd) {
+				t.m.Set(fd, tmp.Get(fd))
+			} else {
+				t.m.Clear(fd)
+			}
+			return nil
+		}
+		pv = t.newMessage()
+		if err := d.decodeMessage(pv.Message(), n, false); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case t.key.IsValid():
+		t.m.Mutable(t.fd).Map().Set(t.key, pv)
+	case t.index >= 0:
+		l := t.m.Mutable(t.fd).List()
+		if t.index == l.Len() {
+			l.Append(pv)
+		} else {
+			l.Set(t.index, pv)
+		}
+	default:
+		t.m.Set(t.fd, pv)
+	}
+	return nil
+}
+
+// newMessage returns a new message of the target type.
+func (t pathTarget) newMessage() protoreflect.Value {
+	switch {
+	case t.key.IsValid():
+		return t.m.Mutable(t.fd).Map().NewValue()
+	case t.index >= 0:
+		return t.m.Mutable(t.fd).List().NewElement()
+	default:
+		return t.m.NewField(t.fd)
+	}
+}
+
+// mutableMessage returns the message held by the target, to descend
+// into. A missing message is created, and returned with a function
+// that adds it to the target. The function is nil if the message
+// exists.
+func (t pathTarget) mutableMessage() (protoreflect.Message, func()) {
+	switch {
+	case t.key.IsValid():
+		if t.m.Get(t.fd).Map().Has(t.key) {
+			return t.m.Mutable(t.fd).Map().Mutable(t.key).Message(), nil
+		}
+		v := t.newMessage()
+		return v.Message(), func() { t.m.Mutable(t.fd).Map().Set(t.key, v) }
+	case t.index >= 0:
+		if t.index < t.m.Get(t.fd).List().Len() {
+			return t.m.Mutable(t.fd).List().Get(t.index).Message(), nil
+		}
+		v := t.newMessage()
+		return v.Message(), func() { t.m.Mutable(t.fd).List().Append(v) }
+	default:
+		if t.m.Has(t.fd) {
+			return t.m.Mutable(t.fd).Message(), nil
+		}
+		v := t.newMessage()
+		return v.Message(), func() { t.m.Set(t.fd, v) }
+	}
+}
+
+// walkPath resolves a field path in m, and calls f with the target.
+// Extension fields not set in m are resolved through xr. If create is
+// true, parent messages, list elements and map entries are created,
+// and a list index may be the length of the list. They are only added
+// to m if f succeeds.
+func walkPath(m protoreflect.Message, path string, xr protoregistry.ExtensionTypeResolver, create bool, f func(pathTarget) error) error {
+	steps, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+
+	var prefix string
+	var adds []func()
+	for len(steps) > 0 {
+		fd, err := findPathField(m, steps[0].name, xr)
+		if err != nil {
+			return err
+		}
+		prefix = fieldPath(prefix, fd)
+		t := pathTarget{m: m, fd: fd, path: prefix, index: -1}
+		steps = steps[1:]
+
+		if len(steps) > 0 && steps[0].isKey {
+			if t, prefix, err = pathElement(t, prefix, steps[0].key, create); err != nil {
+				return err
+			}
+			t.path = prefix
+			steps = steps[1:]
+		} else if len(steps) > 0 && (fd.IsList() || fd.IsMap()) {
+			return fmt.Errorf("protoyaml: %s: missing index or key", prefix)
+		}
+		if len(steps) == 0 {
+			if err := f(t); err != nil {
+				return err
+			}
+			for _, add := range adds {
+				add()
+			}
+			return nil
+		}
+
+		vfd := fd
+		if fd.IsMap() {
+			vfd = fd.MapValue()
+		}
+		if !isMessageKind(vfd) || isKnownType(vfd.Message()) {
+			return fmt.Errorf("protoyaml: %s: not a message with fields", prefix)
+		}
+		if !create {
+			m = t.get().Message()
+		} else {
+			var add func()
+			if m, add = t.mutableMessage(); add != nil {
+				adds = append(adds, add)
+			}
+		}
+	}
+	return nil
+}
+
+// pathElement returns the target for an index or key step, and the
+// updated path prefix. When creating, the index may be the length of
+// the list, and the map key may be missing.
+func pathElement(t pathTarget, prefix, key string, create bool) (pathTarget, string, error) {
+	switch {
+	case t.fd.IsList():
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 {
+			return t, prefix, fmt.Errorf("protoyaml: %s: invalid list index %q", prefix, key)
+		}
+		prefix = listPath(prefix, i)
+		n := t.m.Get(t.fd).List().Len()
+		switch {
+		case i < n:
+		case create && i == n:
+		default:
+			return t, prefix, fmt.Errorf("protoyaml: %s: index out of range, the length is %d", prefix, n)
+		}
+		t.index = i
+		return t, prefix, nil
+
+	case t.fd.IsMap():
+		kv, err := NewDecoder(nil).decodeValue(t.fd.MapKey(), &yaml.Node{Kind: yaml.ScalarNode, Value: key})
+		if err != nil {
+			return t, prefix, fmt.Errorf("protoyaml: %s: invalid map key %q: %w", prefix, key, err)
+		}
+		t.key = kv.MapKey()
+		prefix = mapPath(prefix, t.key)
+		if !create && !t.m.Get(t.fd).Map().Has(t.key) {
+			return t, prefix, fmt.Errorf("protoyaml: %s: no such map key", prefix)
+		}
+		return t, prefix, nil
+
+	default:
+		return t, prefix, fmt.Errorf("protoyaml: %s: not a list or map", prefix)
+	}
+}
+
+// findPathField returns the field named by a path step. Extension
+// fields are written as "(full.name)", and are resolved through xr if
+// they are not set in m.
+func findPathField(m protoreflect.Message, name string, xr protoregistry.ExtensionTypeResolver) (protoreflect.FieldDescriptor, error) {
+	md := m.Descriptor()
+	if !strings.HasPrefix(name, "(") {
+		fd := md.Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			fd = findAliasedField(md, name)
+		}
+		if fd == nil {
+			return nil, fmt.Errorf("protoyaml: unknown field: %s.%s", md.FullName(), name)
+		}
+		return fd, nil
+	}
+
+	xname := protoreflect.FullName(strings.TrimSuffix(strings.TrimPrefix(name, "("), ")"))
+	var xd protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		if fd.IsExtension() && fd.FullName() == xname {
+			xd = fd
+			return false
+		}
+		return true
+	})
+	if xd != nil {
+		return xd, nil
+	}
+	xt, err := xr.FindExtensionByName(xname)
+	if err != nil {
+		return nil, fmt.Errorf("protoyaml: unknown extension field: %s%s: %w", md.FullName(), name, err)
+	}
+	if xt.TypeDescriptor().ContainingMessage().FullName() != md.FullName() {
+		return nil, fmt.Errorf("protoyaml: extension field %s does not extend %s", xname, md.FullName())
+	}
+	return xt.TypeDescriptor(), nil
+}
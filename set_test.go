@@ -0,0 +1,289 @@
+package protoyaml
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tommie/protoyaml-go/internal/testproto"
+)
+
+func TestGetPath(t *testing.T) {
+	m := &testproto.Message{
+		Anint32:           42,
+		ArepeatedString:   []string{"a", "b"},
+		AstringMessageMap: map[string]*testproto.Message{"a.b": {Anenum: testproto.Enum_ONE}},
+	}
+
+	tsts := []struct {
+		Path string
+		Want interface{}
+	}{
+		{"anint32", int32(42)},
+		{"astring", ""},
+		{"arepeated_string[1]", "b"},
+		{`astring_message_map["a.b"].anenum`, testproto.Enum_ONE.Number()},
+		{"amessage.anint32", int32(0)},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Path, func(t *testing.T) {
+			got, err := GetPath(m, tst.Path)
+			if err != nil {
+				t.Fatalf("GetPath failed: %v", err)
+			}
+			if got.Interface() != tst.Want {
+				t.Errorf("GetPath: got %v, want %v", got.Interface(), tst.Want)
+			}
+		})
+	}
+}
+
+func TestGetPathNode(t *testing.T) {
+	m := &testproto.Message{AstringInt32Map: map[string]int32{"b": 2, "a": 1}}
+
+	n, err := GetPathNode(m, "astring_int32_map")
+	if err != nil {
+		t.Fatalf("GetPathNode failed: %v", err)
+	}
+	got, err := yaml.Marshal(n)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if diff := cmp.Diff("a: 1\nb: 2\n", string(got)); diff != "" {
+		t.Errorf("GetPathNode: +got, -want:\n%s", diff)
+	}
+}
+
+func TestGetPathError(t *testing.T) {
+	m := &testproto.Message{ArepeatedString: []string{"a"}}
+
+	tsts := []struct {
+		Path string
+		Want string
+	}{
+		{"nosuch", "protoyaml: unknown field: protoyaml.test.Message.nosuch"},
+		{"arepeated_string[1]", "protoyaml: arepeated_string[1]: index out of range, the length is 1"},
+		{`astring_int32_map["a"]`, `protoyaml: astring_int32_map["a"]: no such map key`},
+		{"arepeated_string.x", "protoyaml: arepeated_string: missing index or key"},
+		{"anint32.x", "protoyaml: anint32: not a message with fields"},
+		{"anint32[0]", "protoyaml: anint32: not a list or map"},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Path, func(t *testing.T) {
+			_, err := GetPath(m, tst.Path)
+			if err == nil || err.Error() != tst.Want {
+				t.Errorf("GetPath: got %v, want %q", err, tst.Want)
+			}
+		})
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	tsts := []struct {
+		Path  string
+		Value string
+		Want  *testproto.Message
+	}{
+		{"anint32", "42", &testproto.Message{Anint32: 42}},
+		{"astring", "true", &testproto.Message{Astring: "true"}},
+		{"anenum", "ONE", &testproto.Message{Anenum: testproto.Enum_ONE}},
+		{"anold", "a", &testproto.Message{Arenamed: "a"}},
+		{"amessage.amessage.abool", "true", &testproto.Message{Amessage: &testproto.Message{Amessage: &testproto.Message{Abool: true}}}},
+		{"arepeated_string[0]", "x", &testproto.Message{ArepeatedString: []string{"x", "b"}}},
+		{"arepeated_string[2]", "c", &testproto.Message{ArepeatedString: []string{"a", "b", "c"}}},
+		{"arepeated_string", "[c]", &testproto.Message{ArepeatedString: []string{"c"}}},
+		{`astring_int32_map["k"]`, "1", &testproto.Message{AstringInt32Map: map[string]int32{"k": 1}}},
+		{`astring_message_map["k"].anint32`, "1", &testproto.Message{AstringMessageMap: map[string]*testproto.Message{"k": {Anint32: 1}}}},
+		{"amessage", "{astring: a}", &testproto.Message{Amessage: &testproto.Message{Astring: "a"}}},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Path, func(t *testing.T) {
+			m := &testproto.Message{}
+			if tst.Path == "arepeated_string[0]" || tst.Path == "arepeated_string[2]" {
+				m.ArepeatedString = []string{"a", "b"}
+			}
+			if err := SetPath(m, tst.Path, tst.Value); err != nil {
+				t.Fatalf("SetPath failed: %v", err)
+			}
+			if diff := cmp.Diff(tst.Want, m, protocmp.Transform()); diff != "" {
+				t.Errorf("SetPath: +got, -want:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSetPathError(t *testing.T) {
+	tsts := []struct {
+		Path  string
+		Value string
+		Want  string
+	}{
+		{"anint32", "x", "protoyaml: anint32: cannot unmarshal !!str `x` into int32"},
+		{"arepeated_string[1]", "x", "protoyaml: arepeated_string[1]: index out of range, the length is 0"},
+		{"amessage", "[]", "protoyaml: amessage: attempting to decode a 2 into a message: protoyaml.test.Message"},
+		{"amessage.amessage.anint32", "x", "protoyaml: amessage.amessage.anint32: cannot unmarshal !!str `x` into int32"},
+		{"arepeated_message[0].anint32", "x", "protoyaml: arepeated_message[0].anint32: cannot unmarshal !!str `x` into int32"},
+		{`astring_message_map["k"].anint32`, "x", "protoyaml: astring_message_map[\"k\"].anint32: cannot unmarshal !!str `x` into int32"},
+		{"arepeated_int32", "[1, x]", "protoyaml: arepeated_int32: cannot unmarshal !!str `x` into int32"},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Path, func(t *testing.T) {
+			m := &testproto.Message{}
+			err := SetPath(m, tst.Path, tst.Value)
+			if err == nil || err.Error() != tst.Want {
+				t.Errorf("SetPath: got %v, want %q", err, tst.Want)
+			}
+			if diff := cmp.Diff(&testproto.Message{}, m, protocmp.Transform()); diff != "" {
+				t.Errorf("SetPath changed the message: +got, -want:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecoderSetPathExtension(t *testing.T) {
+	md := compileProto(t, `syntax = "proto2";
+package conf;
+
+message Server {
+  extensions 100 to 200;
+}
+
+message Tls {
+  optional string cert = 1;
+}
+
+extend Server {
+  optional Tls tls = 100;
+}
+`, "conf.Server")
+	var files protoregistry.Files
+	if err := files.RegisterFile(md.ParentFile()); err != nil {
+		t.Fatalf("RegisterFile failed: %v", err)
+	}
+
+	m := dynamicpb.NewMessage(md)
+	if err := SetPath(m, "(conf.tls).cert", "a.pem"); err == nil {
+		t.Fatalf("SetPath: got nil error, want an unknown extension")
+	}
+	if _, err := GetPath(m, "(conf.tls).cert"); err == nil {
+		t.Fatalf("GetPath: got nil error, want an unknown extension")
+	}
+
+	d := NewDecoder(nil)
+	d.ExtensionTypeResolver(dynamicpb.NewTypes(&files))
+	if got, err := d.GetPath(m, "(conf.tls).cert"); err != nil {
+		t.Fatalf("GetPath failed: %v", err)
+	} else if got.String() != "" {
+		t.Errorf("GetPath: got %v, want the default", got)
+	}
+	if err := d.SetPath(m, "(conf.tls).cert", "a.pem"); err != nil {
+		t.Fatalf("SetPath failed: %v", err)
+	}
+	got, err := GetPath(m, "(conf.tls).cert")
+	if err != nil {
+		t.Fatalf("GetPath failed: %v", err)
+	}
+	if got.String() != "a.pem" {
+		t.Errorf("GetPath: got %v, want %q", got, "a.pem")
+	}
+}
+
+func TestSetPathNode(t *testing.T) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte("# Config.\namessage:\n    anint32: 1 # Version.\n"), &doc); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	md := (&testproto.Message{}).ProtoReflect().Descriptor()
+	if err := SetPathNode(&doc, md, "amessage.anint32", "2"); err != nil {
+		t.Fatalf("SetPathNode failed: %v", err)
+	}
+
+	got, err := yaml.Marshal(&doc)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if diff := cmp.Diff("# Config.\namessage:\n    anint32: 2 # Version.\n", string(got)); diff != "" {
+		t.Errorf("SetPathNode: +got, -want:\n%s", diff)
+	}
+}
+
+func TestDecoderGetPathNodeAny(t *testing.T) {
+	md := compileProto(t, `syntax = "proto3";
+package conf;
+import "google/protobuf/any.proto";
+
+message Server {
+  google.protobuf.Any extra = 1;
+}
+
+message Tls {
+  string cert = 1;
+}
+`, "conf.Server")
+	tmd := md.ParentFile().Messages().ByName("Tls")
+	tls := dynamicpb.NewMessage(tmd)
+	tls.Set(tmd.Fields().ByName("cert"), protoreflect.ValueOfString("a.pem"))
+	bs, err := proto.Marshal(tls)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	m := dynamicpb.NewMessage(md)
+	fd := md.Fields().ByName("extra")
+	any := m.NewField(fd).Message()
+	any.Set(any.Descriptor().Fields().ByName("type_url"), protoreflect.ValueOfString("type.googleapis.com/conf.Tls"))
+	any.Set(any.Descriptor().Fields().ByName("value"), protoreflect.ValueOfBytes(bs))
+	m.Set(fd, protoreflect.ValueOfMessage(any))
+
+	if _, err := GetPathNode(m, "extra"); err == nil {
+		t.Fatalf("GetPathNode: got nil error, want an unknown message type")
+	}
+
+	var types protoregistry.Types
+	if err := types.RegisterMessage(dynamicpb.NewMessageType(tmd)); err != nil {
+		t.Fatalf("RegisterMessage failed: %v", err)
+	}
+	d := NewDecoder(nil)
+	d.MessageTypeResolver(&types)
+	n, err := d.GetPathNode(m, "extra")
+	if err != nil {
+		t.Fatalf("GetPathNode failed: %v", err)
+	}
+	got, err := yaml.Marshal(n)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if diff := cmp.Diff("'@type': type.googleapis.com/conf.Tls\ncert: a.pem\n", string(got)); diff != "" {
+		t.Errorf("GetPathNode: +got, -want:\n%s", diff)
+	}
+}
+
+func TestDecoderSetPathNodeTag(t *testing.T) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte("anint32: !num one\nastring: a\n"), &doc); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	d := NewDecoder(nil)
+	d.RegisterTag("!num", func(n *yaml.Node) (*yaml.Node, error) {
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: "1"}, nil
+	})
+	md := (&testproto.Message{}).ProtoReflect().Descriptor()
+	if err := d.SetPathNode(&doc, md, "astring", "b"); err != nil {
+		t.Fatalf("SetPathNode failed: %v", err)
+	}
+
+	got, err := yaml.Marshal(&doc)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if diff := cmp.Diff("anint32: !num one\nastring: b\n", string(got)); diff != "" {
+		t.Errorf("SetPathNode: +got, -want:\n%s", diff)
+	}
+}
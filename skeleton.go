@@ -0,0 +1,179 @@
+package protoyaml
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+)
+
+// Skeleton returns an example YAML document for md, showing the
+// fields that exist. See SkeletonNode.
+func Skeleton(md protoreflect.MessageDescriptor, depth int) ([]byte, error) {
+	return yaml.Marshal(SkeletonNode(md, depth))
+}
+
+// SkeletonNode returns an example YAML document for md, with every
+// field set to its default value, or a placeholder. The .proto
+// comments of fields are added as YAML comments, if the descriptors
+// have source info. Enum alternatives, required fields and oneof
+// alternatives are noted in comments. Only the first field of a
+// oneof is included, and deprecated fields are left out. Nested
+// messages are expanded down to depth levels below the root, and are
+// empty mappings below that.
+func SkeletonNode(md protoreflect.MessageDescriptor, depth int) *yaml.Node {
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{skeletonMessage(md, depth)}}
+}
+
+// skeletonMessage returns an example message.
+func skeletonMessage(md protoreflect.MessageDescriptor, depth int) *yaml.Node {
+	switch md.FullName() {
+	case anyName:
+		n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		n.Content = append(n.Content, stringNode("@type"), stringNode("type.googleapis.com/full.Name"))
+		n.Content[1].LineComment = "# and the fields of the message"
+		return n
+	case durationName:
+		return stringNode("0s")
+	case fieldMaskName:
+		return &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle}
+	case timestampName:
+		return stringNode("1970-01-01T00:00:00Z")
+	}
+
+	n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if depth < 0 {
+		n.Style = yaml.FlowStyle
+		return n
+	}
+
+	fds := md.Fields()
+	for i := 0; i < fds.Len(); i++ {
+		fd := fds.Get(i)
+		if isDeprecatedField(fd) {
+			continue
+		}
+		var notes []string
+		if od := fd.ContainingOneof(); od != nil && !od.IsSynthetic() {
+			if od.Fields().Get(0) != fd {
+				continue
+			}
+			notes = append(notes, "oneof "+oneofAlternatives(od))
+		}
+		if fd.Cardinality() == protoreflect.Required {
+			notes = append(notes, "required")
+		}
+
+		kn := stringNode(fieldKey(fd))
+		kn.HeadComment = yamlComment(fd.ParentFile().SourceLocations().ByDescriptor(fd).LeadingComments)
+		vn := skeletonField(fd, depth)
+		if ed := fieldEnum(fd); ed != nil {
+			alts := "one of " + enumAlternatives(ed)
+			if fd.IsList() || fd.IsMap() {
+				// Block collections can't have line comments, so it
+				// goes on the element.
+				vn.Content[len(vn.Content)-1].LineComment = "# " + alts
+			} else {
+				notes = append(notes, alts)
+			}
+		}
+		if len(notes) > 0 {
+			vn.LineComment = "# " + strings.Join(notes, "; ")
+		}
+		n.Content = append(n.Content, kn, vn)
+	}
+	return n
+}
+
+// skeletonField returns an example value of a field, which may be a
+// map or a list with one element.
+func skeletonField(fd protoreflect.FieldDescriptor, depth int) *yaml.Node {
+	switch {
+	case fd.IsMap():
+		kn := skeletonValue(fd.MapKey(), depth)
+		if fd.MapKey().Kind() == protoreflect.StringKind {
+			kn = stringNode("key")
+		}
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{kn, skeletonValue(fd.MapValue(), depth)}}
+
+	case fd.IsList():
+		return &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{skeletonValue(fd, depth)}}
+
+	default:
+		return skeletonValue(fd, depth)
+	}
+}
+
+// skeletonValue returns an example singular value of a field.
+func skeletonValue(fd protoreflect.FieldDescriptor, depth int) *yaml.Node {
+	if isMessageKind(fd) {
+		return skeletonMessage(fd.Message(), depth-1)
+	}
+	v := fd.Default()
+	if fd.IsList() {
+		v = zeroValue(fd)
+	}
+	n, err := NewEncoder(nil).encodeValue(fd, v)
+	if err != nil {
+		return scalarNode("!!null", "null")
+	}
+	return n
+}
+
+// zeroValue returns the zero value of a non-message field, as if it
+// was singular. For enums, it is the first value, since a closed enum
+// may not have zero.
+func zeroValue(fd protoreflect.FieldDescriptor) protoreflect.Value {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(false)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(0)
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(0)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(0)
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(0)
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(0)
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(0)
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString("")
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes(nil)
+	case protoreflect.EnumKind:
+		return protoreflect.ValueOfEnum(fd.Enum().Values().Get(0).Number())
+	default:
+		return protoreflect.Value{}
+	}
+}
+
+// fieldEnum returns the enum of a field, or map value, or nil.
+func fieldEnum(fd protoreflect.FieldDescriptor) protoreflect.EnumDescriptor {
+	if fd.IsMap() {
+		fd = fd.MapValue()
+	}
+	return fd.Enum()
+}
+
+// enumAlternatives returns the value names of an enum.
+func enumAlternatives(ed protoreflect.EnumDescriptor) string {
+	var names []string
+	evds := ed.Values()
+	for i := 0; i < evds.Len(); i++ {
+		names = append(names, string(evds.Get(i).Name()))
+	}
+	return strings.Join(names, ", ")
+}
+
+// oneofAlternatives returns the name of a oneof, and its fields.
+func oneofAlternatives(od protoreflect.OneofDescriptor) string {
+	var names []string
+	fds := od.Fields()
+	for i := 0; i < fds.Len(); i++ {
+		names = append(names, string(fds.Get(i).Name()))
+	}
+	return string(od.Name()) + ": " + strings.Join(names, ", ")
+}
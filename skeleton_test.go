@@ -0,0 +1,93 @@
+package protoyaml
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestSkeleton(t *testing.T) {
+	md := compileProto(t, `syntax = "proto2";
+package conf;
+import "google/protobuf/duration.proto";
+
+message Server {
+  // Where to listen.
+  //
+  // Defaults to all interfaces.
+  required string listen = 1;
+  optional google.protobuf.Duration timeout = 2;
+  repeated Backend backends = 3;
+  map<string, Level> levels = 4;
+  optional int32 port = 5 [default = 80];
+  required Level level = 9 [default = HIGH];
+  optional bytes key = 6 [deprecated = true];
+  oneof auth {
+    string password = 7;
+    string token = 8;
+  }
+}
+
+message Backend {
+  optional string address = 1;
+  optional Backend fallback = 2;
+}
+
+enum Level {
+  LOW = 1;
+  HIGH = 2;
+}
+`, "conf.Server")
+
+	tsts := []struct {
+		Name  string
+		Depth int
+		Want  string
+	}{
+		{"depth0", 0, `# Where to listen.
+#
+# Defaults to all interfaces.
+listen: "" # required
+timeout: 0s
+backends:
+    - {}
+levels:
+    key: LOW # one of LOW, HIGH
+port: 80
+level: HIGH # required; one of LOW, HIGH
+password: "" # oneof auth: password, token
+`},
+		{"depth2", 2, `# Where to listen.
+#
+# Defaults to all interfaces.
+listen: "" # required
+timeout: 0s
+backends:
+    - address: ""
+      fallback:
+        address: ""
+        fallback: {}
+levels:
+    key: LOW # one of LOW, HIGH
+port: 80
+level: HIGH # required; one of LOW, HIGH
+password: "" # oneof auth: password, token
+`},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Name, func(t *testing.T) {
+			bs, err := Skeleton(md, tst.Depth)
+			if err != nil {
+				t.Fatalf("Skeleton failed: %v", err)
+			}
+			if diff := cmp.Diff(tst.Want, string(bs)); diff != "" {
+				t.Errorf("Skeleton: +got, -want:\n%s", diff)
+			}
+
+			if err := Unmarshal(bs, dynamicpb.NewMessage(md)); err != nil {
+				t.Errorf("Unmarshal failed: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package protoyaml
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// A Position is a location in a YAML text.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// String returns the position as "file:line:column", leaving out the
+// file name if empty.
+func (p Position) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// A SourceInfo maps field paths of a decoded message to where they
+// were written. Paths use Protobuf names, list indices and map keys,
+// e.g. `amessage.arepeated_message[2].astring_int32_map["key"]`.
+// Extension fields are written as "(full.name)". Fields, list
+// elements and map entries map to the position of their key, or of
+// the element itself in a sequence.
+type SourceInfo struct {
+	Positions map[string]Position
+
+	md protoreflect.MessageDescriptor
+}
+
+func newSourceInfo(md protoreflect.MessageDescriptor) *SourceInfo {
+	return &SourceInfo{Positions: map[string]Position{}, md: md}
+}
+
+// Lookup returns the position of a field path.
+func (si *SourceInfo) Lookup(path string) (Position, bool) {
+	p, ok := si.Positions[path]
+	return p, ok
+}
+
+// FieldMask returns the fields that were written in the document.
+// This can tell an explicit zero value apart from an omitted field,
+// even without field presence. Only the most specific paths are
+// included, e.g. "amessage.anint32" rather than "amessage". Since
+// field masks cannot address list elements or map entries, repeated
+// and map fields are included as a whole. Well-known types and
+// extension fields are also included as a whole, with extensions
+// written as "(full.name)".
+func (si *SourceInfo) FieldMask() *fieldmaskpb.FieldMask {
+	seen := map[string]bool{}
+	var paths []string
+	for p := range si.Positions {
+		mp, ok := si.maskPath(p)
+		if !ok || seen[mp] {
+			continue
+		}
+		seen[mp] = true
+		paths = append(paths, mp)
+	}
+
+	// Field names sort after ".", so any paths below p come right
+	// after it.
+	sort.Strings(paths)
+	var fm fieldmaskpb.FieldMask
+	for i, p := range paths {
+		if i+1 < len(paths) && strings.HasPrefix(paths[i+1], p+".") {
+			continue
+		}
+		fm.Paths = append(fm.Paths, p)
+	}
+	return &fm
+}
+
+// maskPath truncates a path to what a field mask can express. It
+// returns false if the path cannot be parsed.
+func (si *SourceInfo) maskPath(path string) (string, bool) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return "", false
+	}
+
+	md := si.md
+	var names []string
+	for _, st := range steps {
+		if st.isKey {
+			break
+		}
+		names = append(names, st.name)
+		if md == nil {
+			break
+		}
+		fd := md.Fields().ByName(protoreflect.Name(st.name))
+		if fd == nil || fd.IsList() || fd.IsMap() || !isMessageKind(fd) || isKnownType(fd.Message()) {
+			break
+		}
+		md = fd.Message()
+	}
+	return strings.Join(names, "."), true
+}
+
+// record remembers the position of path. It does nothing if si is
+// nil.
+func (si *SourceInfo) record(path string, pos Position) {
+	if si == nil {
+		return
+	}
+	si.Positions[path] = pos
+}
+
+// forget removes the positions of path and everything below it, when
+// a field is overwritten. It does nothing if si is nil.
+func (si *SourceInfo) forget(path string) {
+	if si == nil {
+		return
+	}
+	for p := range si.Positions {
+		if isPathPrefix(path, p) {
+			delete(si.Positions, p)
+		}
+	}
+}
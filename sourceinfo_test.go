@@ -0,0 +1,90 @@
+package protoyaml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/tommie/protoyaml-go/internal/testproto"
+)
+
+func TestSourceInfoFieldMask(t *testing.T) {
+	d := NewDecoder(strings.NewReader(`anint32: 0
+amessage: {astring: hello, amessage: {}}
+arepeated_message: [{anint32: 1}]
+astring_message_map: {a: {anint32: 1}}`))
+	d.RecordSourceInfo("")
+
+	var m testproto.Message
+	if err := d.Decode(&m); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	want := []string{"amessage.amessage", "amessage.astring", "anint32", "arepeated_message", "astring_message_map"}
+	if diff := cmp.Diff(want, d.SourceInfo().FieldMask().Paths); diff != "" {
+		t.Errorf("FieldMask: +got, -want:\n%s", diff)
+	}
+
+	d = NewDecoder(strings.NewReader(`{anany: {"@type": type.googleapis.com/protoyaml.test.Message, astring: hello}, aduration: 1s}`))
+	d.RecordSourceInfo("")
+	var k testproto.Known
+	if err := d.Decode(&k); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	want = []string{"aduration", "anany"}
+	if diff := cmp.Diff(want, d.SourceInfo().FieldMask().Paths); diff != "" {
+		t.Errorf("FieldMask: +got, -want:\n%s", diff)
+	}
+
+	si := &SourceInfo{Positions: map[string]Position{`amessage["a`: {}, "anint32": {}}}
+	if diff := cmp.Diff([]string{"anint32"}, si.FieldMask().Paths); diff != "" {
+		t.Errorf("FieldMask: +got, -want:\n%s", diff)
+	}
+}
+
+func TestDecoderSourceInfo(t *testing.T) {
+	d := NewDecoder(strings.NewReader(`astring: hello
+arepeated_message:
+  - anint32: 42
+  - {astring_int32_map: {a: 1}}
+amessage: {<< : {anint32: 1}, anint32: 2}
+astring_message_map: {<< : {a: {anint32: 1}}, a: {astring: x}}
+---
+anint32: 3`))
+	d.RecordSourceInfo("config.yaml")
+
+	var m testproto.Message
+	if err := d.Decode(&m); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+
+	want := map[string]Position{
+		"astring":                                     {"config.yaml", 1, 1},
+		"arepeated_message":                           {"config.yaml", 2, 1},
+		"arepeated_message[0]":                        {"config.yaml", 3, 5},
+		"arepeated_message[0].anint32":                {"config.yaml", 3, 5},
+		"arepeated_message[1]":                        {"config.yaml", 4, 5},
+		"arepeated_message[1].astring_int32_map":      {"config.yaml", 4, 6},
+		`arepeated_message[1].astring_int32_map["a"]`: {"config.yaml", 4, 26},
+		"amessage":                                    {"config.yaml", 5, 1},
+		"amessage.anint32":                            {"config.yaml", 5, 31},
+		"astring_message_map":                         {"config.yaml", 6, 1},
+		`astring_message_map["a"]`:                    {"config.yaml", 6, 47},
+		`astring_message_map["a"].astring`:            {"config.yaml", 6, 51},
+	}
+	if diff := cmp.Diff(want, d.SourceInfo().Positions); diff != "" {
+		t.Errorf("SourceInfo: +got, -want:\n%s", diff)
+	}
+
+	if err := d.Decode(&m); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if got, ok := d.SourceInfo().Lookup("anint32"); !ok || got.String() != "config.yaml:8:1" {
+		t.Errorf("Lookup: got %v, %v, want config.yaml:8:1", got, ok)
+	}
+	if _, ok := d.SourceInfo().Lookup("astring"); ok {
+		t.Errorf("Lookup: got a position from the previous document")
+	}
+}
@@ -0,0 +1,179 @@
+package protoyaml
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A TagHandler produces a replacement for a node with a custom YAML
+// tag, e.g. `!file ./cert.pem`. The replacement is decoded as if it
+// had been written instead of the tagged node. It may itself have a
+// custom tag.
+type TagHandler func(n *yaml.Node) (*yaml.Node, error)
+
+// maxTagDepth limits how many times tag handlers are applied to the
+// same node, to catch handlers that keep returning tagged nodes.
+const maxTagDepth = 32
+
+// RegisterTag sets the handler for a custom tag, including the
+// leading "!". Nodes with that tag are passed to the handler before
+// they are decoded. See EnvTag, FileTag, IncludeTag and SecretTag for
+// built-in handlers.
+func (d *Decoder) RegisterTag(tag string, h TagHandler) {
+	if d.tags == nil {
+		d.tags = map[string]TagHandler{}
+	}
+	d.tags[tag] = h
+}
+
+// resolveNode follows aliases and applies tag handlers until the node
+// is an ordinary node.
+func (d *Decoder) resolveNode(n *yaml.Node) (*yaml.Node, error) {
+	for i := 0; ; i++ {
+		if n.Kind == yaml.AliasNode {
+			n = n.Alias
+			continue
+		}
+		h, ok := d.tags[n.Tag]
+		if !ok {
+			return n, nil
+		}
+		if i >= maxTagDepth {
+			return nil, d.nodeErrorf(n, "too many nested tags: %s", n.Tag)
+		}
+		nn, err := h(n)
+		if err != nil {
+			return nil, d.nodeErrorf(n, "%s %s: %v", n.Tag, n.Value, err)
+		}
+		if d.lookupEnv != nil {
+			if d.tagged == nil {
+				d.tagged = map[*yaml.Node]bool{}
+			}
+			d.tagged[nn] = true
+		}
+		n = nn
+	}
+}
+
+// EnvTag returns a tag handler that replaces a scalar holding a
+// variable name with the value of the variable, e.g.
+// `password: !env DB_PASSWORD`. It is an error if the variable is not
+// set. The lookup function is typically os.LookupEnv.
+func EnvTag(lookup func(string) (string, bool)) TagHandler {
+	return func(n *yaml.Node) (*yaml.Node, error) {
+		if n.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("expected a variable name, got a %v", n.Kind)
+		}
+		v, ok := lookup(n.Value)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s is not set", n.Value)
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: v, Line: n.Line, Column: n.Column}, nil
+	}
+}
+
+// SecretTag returns a tag handler that replaces a scalar holding a
+// secret name with the secret, e.g. `password: !secret db/password`.
+// The lookup function fetches the secret from a secret store, like a
+// vault or a cloud secret manager, and returns an error if it can't.
+// Like EnvTag, the secret is decoded as a plain scalar, so it works
+// for all scalar fields.
+func SecretTag(lookup func(name string) (string, error)) TagHandler {
+	return func(n *yaml.Node) (*yaml.Node, error) {
+		if n.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("expected a secret name, got a %v", n.Kind)
+		}
+		v, err := lookup(n.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: v, Line: n.Line, Column: n.Column}, nil
+	}
+}
+
+// FileTag returns a tag handler that replaces a scalar holding a file
+// name with the contents of the file, e.g. `cert: !file cert.pem`. The
+// contents can be stored in both string and bytes fields.
+func FileTag(fsys fs.FS) TagHandler {
+	return func(n *yaml.Node) (*yaml.Node, error) {
+		if n.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("expected a file name, got a %v", n.Kind)
+		}
+		bs, err := fs.ReadFile(fsys, cleanFSPath(n.Value))
+		if err != nil {
+			return nil, err
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!binary", Value: base64.StdEncoding.EncodeToString(bs), Line: n.Line, Column: n.Column}, nil
+	}
+}
+
+// IncludeTag returns a tag handler that replaces a scalar holding a
+// file name with the first document of that YAML file, e.g.
+// `tls: !include common/tls.yaml`. Included files can use the same
+// tag to include further files, relative to their own directory.
+// Include cycles are reported as errors.
+func IncludeTag(fsys fs.FS) TagHandler {
+	return func(n *yaml.Node) (*yaml.Node, error) {
+		if n.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("expected a file name, got a %v", n.Kind)
+		}
+		return includeFile(fsys, n.Tag, cleanFSPath(n.Value), nil)
+	}
+}
+
+// includeFile reads the first document of a YAML file, and recursively
+// replaces nodes with the given tag by the files they name.
+func includeFile(fsys fs.FS, tag, name string, stack []string) (*yaml.Node, error) {
+	for i, s := range stack {
+		if s == name {
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(append(stack[i:], name), " -> "))
+		}
+	}
+	stack = append(stack, name)
+
+	bs, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(bs, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, fmt.Errorf("%s: empty document", name)
+	}
+
+	var walk func(n *yaml.Node) error
+	walk = func(n *yaml.Node) error {
+		if n.Kind == yaml.ScalarNode && n.Tag == tag {
+			nn, err := includeFile(fsys, tag, cleanFSPath(path.Join(path.Dir(name), n.Value)), stack)
+			if err != nil {
+				return err
+			}
+			*n = *nn
+			return nil
+		}
+		for _, c := range n.Content {
+			if err := walk(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	root := doc.Content[0]
+	if err := walk(root); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// cleanFSPath turns a slash-separated file name into a name accepted
+// by fs.FS, e.g. removing a leading "./".
+func cleanFSPath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
@@ -0,0 +1,102 @@
+package protoyaml
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tommie/protoyaml-go/internal/testproto"
+)
+
+func TestDecoderRegisterTag(t *testing.T) {
+	fsys := fstest.MapFS{
+		"cert.pem":         {Data: []byte("-----BEGIN\x00")},
+		"common/base.yaml": {Data: []byte(`{anint32: 42, amessage: !include msg.yaml}`)},
+		"common/msg.yaml":  {Data: []byte(`astring: included`)},
+		"common/list.yaml": {Data: []byte(`[1, 2]`)},
+	}
+	lookup := func(name string) (string, bool) {
+		if name == "PORT" {
+			return "8080", true
+		}
+		return "", false
+	}
+
+	tsts := []struct {
+		Name string
+		YAML string
+		Want *testproto.Message
+	}{
+		{"env", `anint32: !env PORT`, &testproto.Message{Anint32: 8080}},
+		{"secret", `astring: !secret db/password`, &testproto.Message{Astring: "hunter2"}},
+		{"fileString", `astring: !file ./cert.pem`, &testproto.Message{Astring: "-----BEGIN\x00"}},
+		{"fileBytes", `abytes: !file cert.pem`, &testproto.Message{Abytes: []byte("-----BEGIN\x00")}},
+		{"include", `amessage: !include common/base.yaml`, &testproto.Message{Amessage: &testproto.Message{Anint32: 42, Amessage: &testproto.Message{Astring: "included"}}}},
+		{"includeMerge", `{<< : !include common/msg.yaml, anint32: 1}`, &testproto.Message{Astring: "included", Anint32: 1}},
+		{"includeList", `arepeated_int32: !include common/list.yaml`, &testproto.Message{ArepeatedInt32: []int32{1, 2}}},
+		{"custom", `astring: !upper hello`, &testproto.Message{Astring: "HELLO"}},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Name, func(t *testing.T) {
+			d := NewDecoder(strings.NewReader(tst.YAML))
+			d.RegisterTag("!env", EnvTag(lookup))
+			d.RegisterTag("!secret", SecretTag(func(name string) (string, error) {
+				return map[string]string{"db/password": "hunter2"}[name], nil
+			}))
+			d.RegisterTag("!file", FileTag(fsys))
+			d.RegisterTag("!include", IncludeTag(fsys))
+			d.RegisterTag("!upper", func(n *yaml.Node) (*yaml.Node, error) {
+				return &yaml.Node{Kind: yaml.ScalarNode, Value: strings.ToUpper(n.Value)}, nil
+			})
+
+			var got testproto.Message
+			if err := d.Decode(&got); err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+
+			if diff := cmp.Diff(tst.Want, &got, protocmp.Transform()); diff != "" {
+				t.Errorf("Decode: +got, -want:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecoderRegisterTagError(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.yaml":     {Data: []byte(`amessage: !include sub/b.yaml`)},
+		"sub/b.yaml": {Data: []byte(`amessage: !include ../a.yaml`)},
+	}
+
+	tsts := []struct {
+		Name string
+		YAML string
+		Want string
+	}{
+		{"unsetEnv", `anint32: !env MISSING`, "protoyaml: 1:10: !env MISSING: environment variable MISSING is not set"},
+		{"missingSecret", `astring: !secret db/password`, "protoyaml: 1:10: !secret db/password: permission denied"},
+		{"missingFile", `astring: !file missing.pem`, "protoyaml: 1:10: !file missing.pem: open missing.pem: file does not exist"},
+		{"cycle", `amessage: !include a.yaml`, "protoyaml: 1:11: !include a.yaml: include cycle: a.yaml -> sub/b.yaml -> a.yaml"},
+		{"loop", `astring: !loop x`, "protoyaml: 1:10: too many nested tags: !loop"},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Name, func(t *testing.T) {
+			d := NewDecoder(strings.NewReader(tst.YAML))
+			d.RegisterTag("!env", EnvTag(func(string) (string, bool) { return "", false }))
+			d.RegisterTag("!secret", SecretTag(func(string) (string, error) { return "", errors.New("permission denied") }))
+			d.RegisterTag("!file", FileTag(fsys))
+			d.RegisterTag("!include", IncludeTag(fsys))
+			d.RegisterTag("!loop", func(n *yaml.Node) (*yaml.Node, error) { return n, nil })
+
+			var got testproto.Message
+			err := d.Decode(&got)
+			if err == nil || err.Error() != tst.Want {
+				t.Errorf("Decode: got %v, want %q", err, tst.Want)
+			}
+		})
+	}
+}
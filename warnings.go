@@ -0,0 +1,87 @@
+package protoyaml
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"gopkg.in/yaml.v3"
+)
+
+// A WarningKind classifies a Warning.
+type WarningKind int
+
+const (
+	// WarnDeprecatedField is a field marked `deprecated = true`.
+	WarnDeprecatedField WarningKind = iota
+
+	// WarnDeprecatedEnumValue is an enum value marked
+	// `deprecated = true`.
+	WarnDeprecatedEnumValue
+
+	// WarnUnknownField is a key that was discarded because there is
+	// no such field. See DiscardUnknown.
+	WarnUnknownField
+
+	// WarnFieldAlias is a key using a former field name, as set with
+	// the (protoyaml.field).aliases option.
+	WarnFieldAlias
+)
+
+// A Warning is a problem in the YAML text that did not stop decoding.
+type Warning struct {
+	Kind WarningKind
+
+	// Position is where the key or value was written.
+	Position Position
+
+	// Path is the field path of the value. For an unknown field, it
+	// is the path the field would have had.
+	Path string
+
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%v: %s: %s", w.Position, w.Path, w.Message)
+}
+
+// Warnings sets a function that is called for each warning found
+// while decoding. Decoding continues after a warning.
+func (d *Decoder) Warnings(f func(Warning)) {
+	d.warn = f
+}
+
+// DiscardUnknown makes keys that do not name a field be ignored,
+// instead of failing decoding, like protojson.UnmarshalOptions does.
+// Discarded keys are reported as WarnUnknownField warnings.
+func (d *Decoder) DiscardUnknown(discard bool) {
+	d.discardUnknown = discard
+}
+
+// warnf reports a warning about n at path, if there is a warnings
+// function.
+func (d *Decoder) warnf(n *yaml.Node, kind WarningKind, path string, format string, args ...interface{}) {
+	if d.warn == nil {
+		return
+	}
+	d.warn(Warning{
+		Kind:     kind,
+		Position: d.nodePosition(n),
+		Path:     path,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// isDeprecatedField returns true if the field is marked deprecated.
+func isDeprecatedField(fd protoreflect.FieldDescriptor) bool {
+	opts, ok := fd.Options().(*descriptorpb.FieldOptions)
+	return ok && opts.GetDeprecated()
+}
+
+// isDeprecatedEnumValue returns true if the enum value is marked
+// deprecated.
+func isDeprecatedEnumValue(evd protoreflect.EnumValueDescriptor) bool {
+	opts, ok := evd.Options().(*descriptorpb.EnumValueOptions)
+	return ok && opts.GetDeprecated()
+}
@@ -0,0 +1,91 @@
+package protoyaml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	"github.com/tommie/protoyaml-go/internal/testproto"
+)
+
+func TestDecoderWarnings(t *testing.T) {
+	tsts := []struct {
+		Name    string
+		YAML    string
+		Discard bool
+		Want    *testproto.Message
+		WantW   []Warning
+	}{
+		{
+			Name:  "deprecatedField",
+			YAML:  "amessage:\n  adeprecated: 1\n",
+			Want:  &testproto.Message{Amessage: &testproto.Message{Adeprecated: 1}},
+			WantW: []Warning{{WarnDeprecatedField, Position{"a.yaml", 2, 3}, "amessage.adeprecated", "field protoyaml.test.Message.adeprecated is deprecated"}},
+		},
+		{
+			Name: "deprecatedEnumValue",
+			YAML: "anenum: TWO\narepeated_nenum: [ONE, 2]\n",
+			Want: &testproto.Message{Anenum: testproto.Enum_TWO, ArepeatedNenum: []testproto.Enum{testproto.Enum_ONE, testproto.Enum_TWO}},
+			WantW: []Warning{
+				{WarnDeprecatedEnumValue, Position{"a.yaml", 1, 9}, "anenum", "enum value protoyaml.test.TWO is deprecated"},
+				{WarnDeprecatedEnumValue, Position{"a.yaml", 2, 24}, "arepeated_nenum[1]", "enum value protoyaml.test.TWO is deprecated"},
+			},
+		},
+		{
+			Name:    "unknownField",
+			YAML:    "amessage:\n  nosuchfield: 1\n  '[no.such.ext]': 2\nanint32: 3\n",
+			Discard: true,
+			Want:    &testproto.Message{Amessage: &testproto.Message{}, Anint32: 3},
+			WantW: []Warning{
+				{WarnUnknownField, Position{"a.yaml", 2, 3}, "amessage.nosuchfield", "unknown field protoyaml.test.Message.nosuchfield discarded"},
+				{WarnUnknownField, Position{"a.yaml", 3, 3}, "amessage.(no.such.ext)", "unknown field protoyaml.test.Message.[no.such.ext] discarded"},
+			},
+		},
+		{
+			Name: "none",
+			YAML: "anenum: ONE\n",
+			Want: &testproto.Message{Anenum: testproto.Enum_ONE},
+		},
+	}
+	for _, tst := range tsts {
+		t.Run(tst.Name, func(t *testing.T) {
+			d := NewDecoder(strings.NewReader(tst.YAML))
+			d.RecordSourceInfo("a.yaml")
+			d.DiscardUnknown(tst.Discard)
+			var gotW []Warning
+			d.Warnings(func(w Warning) { gotW = append(gotW, w) })
+
+			var got testproto.Message
+			if err := d.Decode(&got); err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+
+			if diff := cmp.Diff(tst.Want, &got, protocmp.Transform()); diff != "" {
+				t.Errorf("Decode: +got, -want:\n%s", diff)
+			}
+			if diff := cmp.Diff(tst.WantW, gotW); diff != "" {
+				t.Errorf("Warnings: +got, -want:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecoderDiscardUnknownUnset(t *testing.T) {
+	d := NewDecoder(strings.NewReader("nosuchfield: 1\n"))
+	d.Warnings(func(w Warning) { t.Errorf("unexpected warning: %v", w) })
+
+	var got testproto.Message
+	err := d.Decode(&got)
+	if want := "protoyaml: 1:1: unknown field: protoyaml.test.Message.nosuchfield"; err == nil || err.Error() != want {
+		t.Errorf("Decode: got %v, want %q", err, want)
+	}
+}
+
+func TestWarningString(t *testing.T) {
+	w := Warning{WarnDeprecatedField, Position{"a.yaml", 2, 3}, "amessage.adeprecated", "field is deprecated"}
+	if got, want := w.String(), "a.yaml:2:3: amessage.adeprecated: field is deprecated"; got != want {
+		t.Errorf("String: got %q, want %q", got, want)
+	}
+}